@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/provider"
+	"go-artisan/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+// 使用:
+//   go run cmd/artisan/main.go permission:grant role:sales /api/orders/:id GET
+//   go run cmd/artisan/main.go role:assign 1 sales
+//   go run cmd/artisan/main.go permission:check user:1 /api/orders/42 GET
+//
+// 所有命令都通过 provider.NewCasbinEnforcer 构建 Enforcer，与 Server 使用同一份 RBAC 模型
+
+// NewPermissionGrantCommand 新增策略
+func NewPermissionGrantCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "permission:grant [subject] [object] [action]",
+		Short: "Grant a permission to a user or role (e.g. role:sales /api/orders/:id GET)",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := newPermissionService(cfg)
+			policy := service.Policy{Subject: parseSubject(args[0]), Object: args[1], Action: args[2]}
+
+			added, err := svc.AddPolicy(policy)
+			if err != nil {
+				fmt.Printf("❌ Failed to grant permission: %v\n", err)
+				os.Exit(1)
+			}
+			if !added {
+				fmt.Printf("⚠️  Permission already exists: %s %s %s\n", policy.Subject, policy.Object, policy.Action)
+				return
+			}
+			fmt.Printf("✅ Granted: %s %s %s\n", policy.Subject, policy.Object, strings.ToUpper(policy.Action))
+		},
+	}
+}
+
+// NewPermissionRevokeCommand 删除策略
+func NewPermissionRevokeCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "permission:revoke [subject] [object] [action]",
+		Short: "Revoke a permission from a user or role",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := newPermissionService(cfg)
+			policy := service.Policy{Subject: parseSubject(args[0]), Object: args[1], Action: args[2]}
+
+			removed, err := svc.RemovePolicy(policy)
+			if err != nil {
+				fmt.Printf("❌ Failed to revoke permission: %v\n", err)
+				os.Exit(1)
+			}
+			if !removed {
+				fmt.Printf("⚠️  Permission not found: %s %s %s\n", policy.Subject, policy.Object, policy.Action)
+				return
+			}
+			fmt.Printf("✅ Revoked: %s %s %s\n", policy.Subject, policy.Object, strings.ToUpper(policy.Action))
+		},
+	}
+}
+
+// NewRoleAssignCommand 给用户分配角色
+func NewRoleAssignCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "role:assign [user] [role]",
+		Short: "Assign a role to a user (e.g. role:assign 1 admin)",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := newPermissionService(cfg)
+			assignment := service.RoleAssignment{Subject: parseSubject(args[0]), Role: args[1]}
+
+			added, err := svc.AssignRole(assignment)
+			if err != nil {
+				fmt.Printf("❌ Failed to assign role: %v\n", err)
+				os.Exit(1)
+			}
+			if !added {
+				fmt.Printf("⚠️  %s already has role %s\n", assignment.Subject, service.RoleSubject(assignment.Role))
+				return
+			}
+			fmt.Printf("✅ Assigned %s to %s\n", service.RoleSubject(assignment.Role), assignment.Subject)
+		},
+	}
+}
+
+// NewRoleUnassignCommand 收回用户角色
+func NewRoleUnassignCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "role:unassign [user] [role]",
+		Short: "Remove a role from a user",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := newPermissionService(cfg)
+			assignment := service.RoleAssignment{Subject: parseSubject(args[0]), Role: args[1]}
+
+			removed, err := svc.UnassignRole(assignment)
+			if err != nil {
+				fmt.Printf("❌ Failed to unassign role: %v\n", err)
+				os.Exit(1)
+			}
+			if !removed {
+				fmt.Printf("⚠️  %s does not have role %s\n", assignment.Subject, service.RoleSubject(assignment.Role))
+				return
+			}
+			fmt.Printf("✅ Removed %s from %s\n", service.RoleSubject(assignment.Role), assignment.Subject)
+		},
+	}
+}
+
+// NewPermissionListCommand 列出策略
+// 不带参数列出全部策略与角色分配；--user / --role 列出该主体实际拥有的权限 (含继承)
+func NewPermissionListCommand(cfg *config.Config) *cobra.Command {
+	var user, role string
+
+	cmd := &cobra.Command{
+		Use:   "permission:list",
+		Short: "List permissions (all, or effective permissions of --user / --role)",
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := newPermissionService(cfg)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			defer w.Flush()
+
+			sub := ""
+			switch {
+			case user != "":
+				sub = parseSubject(user)
+			case role != "":
+				sub = service.RoleSubject(role)
+			}
+
+			if sub == "" {
+				policies, err := svc.Policies()
+				exitOnError("Failed to list policies", err)
+				assignments, err := svc.RoleAssignments()
+				exitOnError("Failed to list role assignments", err)
+
+				fmt.Fprintln(w, "SUBJECT\tOBJECT\tACTION")
+				for _, p := range policies {
+					fmt.Fprintf(w, "%s\t%s\t%s\n", p.Subject, p.Object, p.Action)
+				}
+				fmt.Fprintln(w, "\nSUBJECT\tROLE\t")
+				for _, a := range assignments {
+					fmt.Fprintf(w, "%s\t%s\t\n", a.Subject, a.Role)
+				}
+				return
+			}
+
+			roles, err := svc.RolesFor(sub)
+			exitOnError("Failed to list roles", err)
+			policies, err := svc.PermissionsFor(sub)
+			exitOnError("Failed to list permissions", err)
+
+			fmt.Fprintf(w, "Subject:\t%s\n", sub)
+			fmt.Fprintf(w, "Roles:\t%s\n\n", strings.Join(roles, ", "))
+			fmt.Fprintln(w, "VIA\tOBJECT\tACTION")
+			for _, p := range policies {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", p.Subject, p.Object, p.Action)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", "", "user id, e.g. --user=1")
+	cmd.Flags().StringVar(&role, "role", "", "role name, e.g. --role=admin")
+	return cmd
+}
+
+// NewPermissionCheckCommand 模拟一次鉴权，排查 "为什么 403"
+func NewPermissionCheckCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "permission:check [subject] [object] [action]",
+		Short: "Check whether a subject is allowed (e.g. user:1 /api/orders GET)",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			svc := newPermissionService(cfg)
+			sub, obj, act := parseSubject(args[0]), args[1], strings.ToUpper(args[2])
+
+			ok, err := svc.Enforce(sub, obj, act)
+			exitOnError("Permission check failed", err)
+
+			if !ok {
+				fmt.Printf("⛔ DENIED: %s %s %s\n", sub, act, obj)
+				os.Exit(1)
+			}
+			fmt.Printf("✅ ALLOWED: %s %s %s\n", sub, act, obj)
+		},
+	}
+}
+
+// newPermissionService 连接数据库并构建与 Server 完全一致的 Enforcer
+// 能连上 Redis 时挂载 Watcher，CLI 的修改会实时广播给所有 Server 节点
+func newPermissionService(cfg *config.Config) *service.PermissionService {
+	ensureDB(cfg)
+
+	db, err := provider.NewDatabase(cfg)
+	exitOnError("Connection failed", err)
+
+	var watcher *provider.CasbinWatcher
+	if rdb, err := provider.NewRedis(cfg); err == nil {
+		watcher = provider.NewCasbinWatcher(rdb, slog.Default())
+	} else {
+		fmt.Printf("⚠️  Redis unavailable, running servers will not see changes until restart: %v\n", err)
+	}
+
+	e, err := provider.NewCasbinEnforcer(db, watcher)
+	exitOnError("Failed to initialize Casbin", err)
+
+	return service.NewPermissionService(e)
+}
+
+// parseSubject 纯数字视为用户 ID：1 -> user:1，其余原样返回 (user:1 / role:admin)
+func parseSubject(arg string) string {
+	if id, err := strconv.ParseUint(arg, 10, 64); err == nil {
+		return service.UserSubject(uint(id))
+	}
+	return arg
+}
+
+// 辅助函数：出错即退出
+func exitOnError(msg string, err error) {
+	if err != nil {
+		fmt.Printf("❌ %s: %v\n", msg, err)
+		os.Exit(1)
+	}
+}
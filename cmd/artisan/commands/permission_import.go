@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/provider"
+	"go-artisan/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+// NewPermissionImportCommand 将仓库中的基线策略同步到 casbin_rule 表
+// 使用:
+//
+//	go run cmd/artisan/main.go permission:import deploy/policies.csv --dry-run
+//	go run cmd/artisan/main.go permission:import deploy/policies.yaml --prune
+//	go run cmd/artisan/main.go permission:import deploy/roles.yaml --prune-roles
+//
+// --prune 只删除 p 规则；角色分配 (g 规则) 平时通过管理接口维护，只有 --prune-roles 才会删除，
+// 且不允许删掉最后一个管理员
+func NewPermissionImportCommand(cfg *config.Config) *cobra.Command {
+	var dryRun, prune, pruneRoles bool
+
+	cmd := &cobra.Command{
+		Use:   "permission:import [file]",
+		Short: "Import Casbin policies from a CSV/YAML file",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			desired, err := service.LoadPolicyFile(args[0])
+			exitOnError("Failed to read policy file", err)
+
+			svc := newPermissionService(cfg)
+			diff, err := svc.Diff(desired, service.DiffOptions{Prune: prune, PruneRoles: pruneRoles})
+			exitOnError("Failed to compare policies", err)
+
+			// 内置管理员策略不允许被 prune 掉，否则管理接口会把所有人拒之门外
+			diff.RemovePolicies = withoutAdminPolicy(diff.RemovePolicies)
+
+			printDiff(os.Stdout, diff)
+			if diff.Empty() {
+				fmt.Println("✅ Policies are already up to date")
+				return
+			}
+			if dryRun {
+				fmt.Println("🔍 Dry run, nothing was written")
+				return
+			}
+
+			exitOnError("Failed to apply policies", svc.Apply(diff))
+			fmt.Printf("✅ Imported: +%d policies, -%d policies, +%d roles, -%d roles\n",
+				len(diff.AddPolicies), len(diff.RemovePolicies), len(diff.AddRoles), len(diff.RemoveRoles))
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the diff against casbin_rule without writing")
+	cmd.Flags().BoolVar(&prune, "prune", false, "remove p rules that are not in the file")
+	cmd.Flags().BoolVar(&pruneRoles, "prune-roles", false, "remove role assignments (g rules) that are not in the file")
+	return cmd
+}
+
+// NewPermissionExportCommand 导出当前策略，不带文件参数时输出到终端
+func NewPermissionExportCommand(cfg *config.Config) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "permission:export [file]",
+		Short: "Export Casbin policies to CSV/YAML (stdout by default)",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			var out io.Writer = os.Stdout
+			if len(args) == 1 {
+				// 有文件名时以扩展名为准
+				if ext := strings.TrimPrefix(filepath.Ext(args[0]), "."); ext != "" {
+					format = ext
+				}
+				f, err := os.Create(args[0])
+				exitOnError("Failed to create file", err)
+				defer f.Close()
+				out = f
+			}
+
+			set, err := newPermissionService(cfg).Snapshot()
+			exitOnError("Failed to load policies", err)
+
+			switch strings.ToLower(format) {
+			case "csv":
+				err = set.WriteCSV(out)
+			case "yaml", "yml":
+				err = set.WriteYAML(out)
+			default:
+				err = fmt.Errorf("unsupported format %q (want csv or yaml)", format)
+			}
+			exitOnError("Failed to export policies", err)
+
+			if len(args) == 1 {
+				fmt.Printf("✅ Exported %d policies and %d role assignments to %s\n", len(set.Policies), len(set.Roles), args[0])
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "csv", "output format when writing to stdout: csv or yaml")
+	return cmd
+}
+
+func printDiff(w io.Writer, diff *service.PolicyDiff) {
+	for _, p := range diff.AddPolicies {
+		fmt.Fprintf(w, "+ p, %s, %s, %s\n", p.Subject, p.Object, p.Action)
+	}
+	for _, g := range diff.AddRoles {
+		fmt.Fprintf(w, "+ g, %s, %s\n", g.Subject, g.Role)
+	}
+	for _, p := range diff.RemovePolicies {
+		fmt.Fprintf(w, "- p, %s, %s, %s\n", p.Subject, p.Object, p.Action)
+	}
+	for _, g := range diff.RemoveRoles {
+		fmt.Fprintf(w, "- g, %s, %s\n", g.Subject, g.Role)
+	}
+}
+
+func withoutAdminPolicy(policies []service.Policy) []service.Policy {
+	admin := service.Policy{Subject: provider.AdminPolicy[0], Object: provider.AdminPolicy[1], Action: provider.AdminPolicy[2]}
+	kept := policies[:0]
+	for _, p := range policies {
+		if p != admin {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"go-artisan/internal/bootstrap"
+	"go-artisan/internal/config"
+	"go-artisan/internal/database/seeders"
+	"go-artisan/internal/provider"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+)
+
+// 使用:
+//   go run cmd/artisan/main.go db:seed                    # 执行全部 Seeder
+//   go run cmd/artisan/main.go db:seed --class=UserSeeder
+//   SEED_ADMIN_PASSWORD=... go run cmd/artisan/main.go db:seed --force   # 生产环境只创建管理员，必须提供密码
+//   go run cmd/artisan/main.go make:seeder Post           # 生成 internal/database/seeders/post_seeder.go
+
+const seederDir = "internal/database/seeders"
+
+const seederTemplate = `package seeders
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type {{.Name}} struct {
+	db *gorm.DB
+	// 这里可以注入 repository / service 依赖
+}
+
+func New{{.Name}}(db *gorm.DB) *{{.Name}} {
+	return &{{.Name}}{db: db}
+}
+
+func (s *{{.Name}}) Run(ctx context.Context) error {
+	// 例如: _, err := factory.New[domain.User]().Count(10).Create(ctx)
+	return nil
+}
+`
+
+// NewDBSeedCommand 执行 Seeder；Seeder 及其依赖由 fx 构建，与 Server 使用相同的 Provider
+func NewDBSeedCommand(cfg *config.Config) *cobra.Command {
+	var (
+		class string
+		force bool
+	)
+	cmd := &cobra.Command{
+		Use:   "db:seed",
+		Short: "Seed the database with records",
+		Run: func(cmd *cobra.Command, args []string) {
+			ensureDB(cfg)
+			if cfg.IsProduction() && !force {
+				fmt.Println("❌ Application is in production, use --force to seed anyway")
+				os.Exit(1)
+			}
+
+			var runner *seeders.Runner
+			app := fx.New(
+				fx.NopLogger,
+				fx.Supply(cfg),
+				fx.Provide(bootstrap.NewLogger),
+				provider.Module,
+				bootstrap.RepositoryModule,
+				bootstrap.ServiceModule,
+				seeders.Module,
+				fx.Populate(&runner),
+			)
+			exitOnError("Failed to initialize", app.Err())
+
+			ctx := context.Background()
+			exitOnError("Failed to start", app.Start(ctx))
+			err := runner.Run(ctx, class)
+			_ = app.Stop(ctx) // exitOnError 直接退出进程，先关闭连接
+			exitOnError("Seeding failed", err)
+			fmt.Println("✅ Database seeded successfully")
+		},
+	}
+	cmd.Flags().StringVar(&class, "class", "", "Run a single seeder, e.g. UserSeeder")
+	cmd.Flags().BoolVar(&force, "force", false, "Allow seeding in production")
+	return cmd
+}
+
+// NewMakeSeederCommand 生成 Seeder 文件
+func NewMakeSeederCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "make:seeder [name]",
+		Short: "Create a new seeder (e.g. make:seeder Post)",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			// Post / post / PostSeeder 都生成 PostSeeder
+			name := strings.TrimSuffix(args[0], "Seeder")
+			if name == "" {
+				fmt.Println("❌ Seeder name is required")
+				os.Exit(1)
+			}
+			name = strings.ToUpper(name[:1]) + name[1:] + "Seeder"
+			fileName := fmt.Sprintf("%s/%s.go", seederDir, toSnakeCase(name))
+
+			if _, err := os.Stat(fileName); err == nil {
+				fmt.Printf("❌ File already exists: %s\n", fileName)
+				os.Exit(1)
+			}
+			exitOnError("Failed to create directory", os.MkdirAll(seederDir, 0755))
+
+			f, err := os.Create(fileName)
+			exitOnError("Failed to create file", err)
+			defer f.Close()
+
+			t := template.Must(template.New("seeder").Parse(seederTemplate))
+			exitOnError("Failed to execute template", t.Execute(f, struct{ Name string }{Name: name}))
+
+			fmt.Printf("✅ Seeder created successfully: %s\n", fileName)
+			fmt.Printf("👉 Don't forget to register it in %s/module.go: fx.Provide(AsSeeder(New%s))\n", seederDir, name)
+		},
+	}
+}
+
+var camelBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// toSnakeCase PostCommentSeeder -> post_comment_seeder
+func toSnakeCase(s string) string {
+	return strings.ToLower(camelBoundary.ReplaceAllString(s, "${1}_${2}"))
+}
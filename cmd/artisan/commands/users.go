@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/provider"
+	"go-artisan/internal/repository"
+	"go-artisan/internal/service"
+
+	"github.com/spf13/cobra"
+)
+
+// 使用:
+//   go run cmd/artisan/main.go users:purge                   # 清除 30 天前注销的账户
+//   go run cmd/artisan/main.go users:purge --older-than=90d
+//   go run cmd/artisan/main.go users:purge --dry-run
+//
+// 建议每天定时执行一次
+
+// NewUsersPurgeCommand 清除保留期已过的注销账户
+func NewUsersPurgeCommand(cfg *config.Config) *cobra.Command {
+	var (
+		olderThan string
+		dryRun    bool
+	)
+	cmd := &cobra.Command{
+		Use:   "users:purge",
+		Short: "Permanently erase users deactivated longer than the retention window",
+		Run: func(cmd *cobra.Command, args []string) {
+			retention, err := parseRetention(olderThan)
+			exitOnError("Invalid --older-than", err)
+			before := time.Now().Add(-retention)
+
+			ctx := context.Background()
+			accounts, users := newAccountService(cfg)
+
+			if dryRun {
+				pending, err := users.FindDeletedBefore(ctx, before, 0, 1000)
+				exitOnError("Failed to list deactivated users", err)
+				fmt.Printf("🔍 %d user(s) deactivated before %s would be purged (showing at most 1000)\n", len(pending), before.Format(time.DateTime))
+				for _, u := range pending {
+					fmt.Printf("   #%d deactivated at %s\n", u.ID, u.DeletedAt.Time.Format(time.DateTime))
+				}
+				return
+			}
+
+			result, err := accounts.Purge(ctx, before)
+			if result != nil && (result.Deleted > 0 || result.Anonymized > 0) {
+				fmt.Printf("🗑  Deleted: %d, anonymized (still referenced): %d\n", result.Deleted, result.Anonymized)
+			}
+			exitOnError("Purge failed", err)
+			fmt.Printf("✅ Purged users deactivated before %s\n", before.Format(time.DateTime))
+		},
+	}
+	cmd.Flags().StringVar(&olderThan, "older-than", "30d", "Retention window, e.g. 30d, 12h")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List the users that would be purged without touching them")
+	return cmd
+}
+
+// parseRetention 在 time.ParseDuration 基础上支持按天表示，如 30d
+func parseRetention(s string) (time.Duration, error) {
+	var (
+		d   time.Duration
+		err error
+	)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q is not a positive duration (e.g. 30d, 12h)", s)
+	}
+	return d, nil
+}
+
+// newAccountService 构建清除账户所需的依赖：数据库、Redis (用户缓存、Token、登录限制) 和 Casbin
+func newAccountService(cfg *config.Config) (*service.AccountService, domain.UserRepository) {
+	ensureDB(cfg)
+
+	db, err := provider.NewDatabase(cfg)
+	exitOnError("Connection failed", err)
+	rdb, err := provider.NewRedis(cfg)
+	exitOnError("Redis connection failed", err)
+
+	e, err := provider.NewCasbinEnforcer(db, provider.NewCasbinWatcher(rdb, slog.Default()))
+	exitOnError("Failed to initialize Casbin", err)
+	tokens, err := service.NewTokenService(cfg, rdb)
+	exitOnError("Failed to initialize token service", err)
+	hasher, err := provider.NewHasher(cfg)
+	exitOnError("Failed to initialize hasher", err)
+
+	users := repository.NewUserRepo(db)
+	accounts := service.NewAccountService(
+		users,
+		repository.NewAPIKeyRepo(db),
+		tokens,
+		nil, // 两步验证只用于注销时确认身份，清除用不到
+		service.NewLoginThrottle(cfg, rdb),
+		service.NewPermissionService(e),
+		hasher,
+		repository.NewTxManager(db),
+		rdb,
+	)
+	return accounts, users
+}
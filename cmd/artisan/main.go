@@ -33,6 +33,21 @@ func main() {
 		commands.NewMakeMigrationCommand(),
 		commands.NewMigrateCommand(cfg),         // 注入 Config
 		commands.NewMigrateRollbackCommand(cfg), // 注入 Config
+		commands.NewMakeSeederCommand(),
+		commands.NewDBSeedCommand(cfg),
+
+		// 权限管理 (Casbin)
+		commands.NewPermissionGrantCommand(cfg),
+		commands.NewPermissionRevokeCommand(cfg),
+		commands.NewPermissionListCommand(cfg),
+		commands.NewPermissionCheckCommand(cfg),
+		commands.NewPermissionImportCommand(cfg),
+		commands.NewPermissionExportCommand(cfg),
+		commands.NewRoleAssignCommand(cfg),
+		commands.NewRoleUnassignCommand(cfg),
+
+		// 用户数据清除
+		commands.NewUsersPurgeCommand(cfg),
 	)
 
 	// 4. 执行
@@ -24,17 +24,39 @@ import (
 // RepositoryModule 定义仓储层的所有注入
 var RepositoryModule = fx.Options(
 	fx.Provide(repository.NewUserRepo),
+	fx.Provide(repository.NewPasswordResetRepo),
+	fx.Provide(repository.NewRecoveryCodeRepo),
+	fx.Provide(repository.NewAPIKeyRepo),
+	fx.Provide(repository.NewOAuthClientRepo),
+	fx.Provide(repository.NewUserIdentityRepo),
+	fx.Provide(repository.NewTxManager), // 跨仓储事务
 )
 
 // ServiceModule 定义服务层的所有注入
 var ServiceModule = fx.Options(
+	fx.Provide(service.NewTokenService),
+	fx.Provide(service.NewLoginThrottle),
+	fx.Provide(service.NewTwoFactorService),
+	fx.Provide(service.NewAPIKeyService),
+	fx.Provide(service.NewOAuthService),
 	fx.Provide(service.NewUserService),
+	fx.Provide(service.NewOIDCService),
+	fx.Provide(service.NewPasswordResetService),
+	fx.Provide(service.NewEmailVerificationService),
+	fx.Provide(service.NewPermissionService),
+	fx.Provide(service.NewAccountService),
 )
 
 // HandlerModule 定义控制器层
 var HandlerModule = fx.Options(
 	fx.Provide(handler.NewWelcomeHandler), // 原来的
 	fx.Provide(handler.NewUserHandler),    // 新增的
+	fx.Provide(handler.NewOrderHandler),
+	fx.Provide(handler.NewPermissionHandler),
+	fx.Provide(handler.NewTwoFactorHandler),
+	fx.Provide(handler.NewAPIKeyHandler),
+	fx.Provide(handler.NewOAuthHandler),
+	fx.Provide(handler.NewOIDCHandler),
 )
 
 var Module = fx.Options(
@@ -48,11 +70,24 @@ var Module = fx.Options(
 
 	router.Module, // 注入 Router (它现在依赖上面的 Handlers)
 
+	// 退出前等待后台发送中的重置密码邮件
+	fx.Invoke(func(lc fx.Lifecycle, resets *service.PasswordResetService) {
+		lc.Append(fx.StopHook(resets.Wait))
+	}),
+
 	// fx.Invoke(Start), // 调用启动逻辑
 )
 
 func NewConfig() (*config.Config, error) {
-	return config.Load("configs/config.yaml")
+	cfg, err := config.Load("configs/config.yaml")
+	if err != nil {
+		return nil, err
+	}
+	// 配置不安全（如生产环境使用默认 JWT 密钥）时拒绝启动
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
 }
 
 func NewLogger(cfg *config.Config) *slog.Logger {
@@ -61,10 +96,10 @@ func NewLogger(cfg *config.Config) *slog.Logger {
 }
 
 // Start 启动 HTTP Server 现在变得更强壮
-func Start(lifecycle fx.Lifecycle, cfg *config.Config, r *gin.Engine) {
+func Start(lifecycle fx.Lifecycle, cfg *config.Config, r *gin.Engine, policy *validator.PasswordPolicy) {
 
 	// 核心修复点：在这里调用独立的初始化
-	validator.Init()
+	validator.Init(policy)
 
 	// 打印版本信息 (炫酷一点)
 	fmt.Println("---------------------------------------------------------")
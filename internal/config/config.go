@@ -1,19 +1,36 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/hash"
+	"go-artisan/pkg/validator"
+
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/joho/godotenv" // 1. 引入库
 	"github.com/spf13/viper"
 )
 
+// DefaultJWTSecret 是仓库 .env 里自带的示例密钥，生产环境禁止使用
+const DefaultJWTSecret = "KeepItSecretKeepItSafe!GoArtisanKey"
+
+// DefaultAppKey 是仓库 .env 里自带的示例 APP_KEY，生产环境禁止使用
+const DefaultAppKey = "GoArtisanLocalAppKeyChangeMeInProduction"
+
 type Config struct {
 	App      AppConfig      `mapstructure:"app"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Redis    RedisConfig    `mapstructure:"redis"` // 👈 新增这一行
+	Auth     AuthConfig     `mapstructure:"auth"`
+	Mail     MailConfig     `mapstructure:"mail"`
+	Hash     HashConfig     `mapstructure:"hash"`
 }
 
 type RedisConfig struct {
@@ -27,6 +44,49 @@ type AppConfig struct {
 	Name string `mapstructure:"name"`
 	Env  string `mapstructure:"env"`
 	Port int    `mapstructure:"port"`
+	URL  string `mapstructure:"url"` // 对外访问地址，用于拼接邮件中的链接
+	Key  string `mapstructure:"key"` // 签名链接 (如邮箱验证) 使用的 HMAC 密钥
+
+	// TrustedProxies 只信任这些反向代理 (IP 或 CIDR) 传来的 X-Forwarded-For / X-Real-IP
+	// 为空时客户端 IP 取 TCP 连接的对端地址，防止伪造请求头绕过按 IP 的登录限制
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+}
+
+// HashConfig 密码哈希配置，切换 driver 后旧哈希在用户下次登录时自动升级
+type HashConfig struct {
+	Driver     string       `mapstructure:"driver"`      // argon2id (默认) / bcrypt
+	BcryptCost int          `mapstructure:"bcrypt_cost"` // bcrypt 使用
+	Argon2     Argon2Config `mapstructure:"argon2"`      // argon2id 使用
+}
+
+type Argon2Config struct {
+	Memory  uint32 `mapstructure:"memory"` // KiB
+	Time    uint32 `mapstructure:"time"`
+	Threads uint8  `mapstructure:"threads"`
+}
+
+// HasherConfig 转换为 pkg/hash 使用的参数
+func (h HashConfig) HasherConfig() hash.Config {
+	return hash.Config{
+		Driver:     h.Driver,
+		BcryptCost: h.BcryptCost,
+		Argon2: hash.Argon2Params{
+			Memory:  h.Argon2.Memory,
+			Time:    h.Argon2.Time,
+			Threads: h.Argon2.Threads,
+		},
+	}
+}
+
+// MailConfig 邮件发送配置
+type MailConfig struct {
+	Driver   string `mapstructure:"driver"`   // log (默认，写日志) / file (写 .eml 文件) / smtp
+	From     string `mapstructure:"from"`     // 发件人
+	FileDir  string `mapstructure:"file_dir"` // file 驱动的输出目录
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
 }
 
 type DatabaseConfig struct {
@@ -36,6 +96,146 @@ type DatabaseConfig struct {
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
 }
 
+// AuthConfig JWT 签发与校验相关配置
+type AuthConfig struct {
+	Algorithm      string   `mapstructure:"algorithm"`        // HS256 (默认) / RS256 / ES256 / EdDSA
+	Secret         string   `mapstructure:"secret"`           // 仅 HS256 使用
+	PrivateKeyFile string   `mapstructure:"private_key_file"` // 非对称算法的签名私钥 (PEM)
+	PublicKeyFiles []string `mapstructure:"public_key_files"` // 密钥轮换期间仍需接受的旧公钥 (PEM)
+
+	TTL        time.Duration `mapstructure:"ttl"`         // Access Token 有效期，支持 "15m" 或纯数字秒数 "900"
+	RefreshTTL time.Duration `mapstructure:"refresh_ttl"` // Refresh Token 有效期
+	Issuer     string        `mapstructure:"issuer"`      // iss
+	Audience   string        `mapstructure:"audience"`    // aud，为空则不校验
+	ClockSkew  time.Duration `mapstructure:"clock_skew"`  // 允许的服务器时钟偏差
+
+	PasswordResetTTL      time.Duration `mapstructure:"password_reset_ttl"`      // 密码重置链接有效期
+	PasswordResetCooldown time.Duration `mapstructure:"password_reset_cooldown"` // 同一邮箱/IP 两次申请重置的最小间隔
+
+	VerificationTTL            time.Duration `mapstructure:"verification_ttl"`             // 邮箱验证链接有效期
+	VerificationResendCooldown time.Duration `mapstructure:"verification_resend_cooldown"` // 重发验证邮件的最小间隔
+
+	MFAChallengeTTL time.Duration `mapstructure:"mfa_challenge_ttl"` // 两步登录中第二步 (输入验证码) 的有效期
+
+	LoginThrottle  LoginThrottleConfig  `mapstructure:"login_throttle"`
+	PasswordPolicy PasswordPolicyConfig `mapstructure:"password_policy"`
+
+	OIDC OIDCConfig `mapstructure:"oidc"`
+}
+
+// OIDCConfig 通过外部 OIDC 身份提供方 (企业 IdP) 登录
+type OIDCConfig struct {
+	Enabled        bool     `mapstructure:"enabled"`
+	Issuer         string   `mapstructure:"issuer"` // 通过 <issuer>/.well-known/openid-configuration 自动发现端点
+	ClientID       string   `mapstructure:"client_id"`
+	ClientSecret   string   `mapstructure:"client_secret"`
+	RedirectURL    string   `mapstructure:"redirect_url"` // 在 IdP 注册的回调地址，指向 /api/auth/oidc/callback
+	Scopes         []string `mapstructure:"scopes"`
+	AutoCreate     bool     `mapstructure:"auto_create"`     // 本地不存在该邮箱时自动创建用户
+	AllowedDomains []string `mapstructure:"allowed_domains"` // 只允许这些邮箱域名登录，为空不限制
+}
+
+// PasswordPolicyConfig 注册和重置密码时的密码强度要求
+type PasswordPolicyConfig struct {
+	MinLength        int    `mapstructure:"min_length"`
+	MaxLength        int    `mapstructure:"max_length"` // 字节数，bcrypt 只使用前 72 字节
+	RequireUpper     bool   `mapstructure:"require_upper"`
+	RequireLower     bool   `mapstructure:"require_lower"`
+	RequireDigit     bool   `mapstructure:"require_digit"`
+	RequireSymbol    bool   `mapstructure:"require_symbol"`
+	DisallowUserInfo bool   `mapstructure:"disallow_user_info"` // 禁止密码包含邮箱或姓名
+	BreachedListFile string `mapstructure:"breached_list_file"` // 常见/泄露密码列表，每行一个
+}
+
+// PolicyConfig 转换为 pkg/validator 使用的参数
+func (p PasswordPolicyConfig) PolicyConfig() validator.PasswordPolicyConfig {
+	return validator.PasswordPolicyConfig{
+		MinLength:        p.MinLength,
+		MaxLength:        p.MaxLength,
+		RequireUpper:     p.RequireUpper,
+		RequireLower:     p.RequireLower,
+		RequireDigit:     p.RequireDigit,
+		RequireSymbol:    p.RequireSymbol,
+		DisallowUserInfo: p.DisallowUserInfo,
+		BreachedListFile: p.BreachedListFile,
+	}
+}
+
+// LoginThrottleConfig 登录防爆破：按账户和按 IP 分别计数
+type LoginThrottleConfig struct {
+	MaxAccountAttempts int           `mapstructure:"max_account_attempts"` // 窗口内同一邮箱允许失败次数，<=0 关闭
+	MaxIPAttempts      int           `mapstructure:"max_ip_attempts"`      // 窗口内同一 IP 允许失败次数，<=0 关闭
+	Window             time.Duration `mapstructure:"window"`               // 失败计数窗口
+	LockoutDuration    time.Duration `mapstructure:"lockout_duration"`     // 达到阈值后锁定时长
+	BaseDelay          time.Duration `mapstructure:"base_delay"`           // 渐进延迟：第 n 次失败等待 base_delay * 2^(n-1)
+	MaxDelay           time.Duration `mapstructure:"max_delay"`            // 渐进延迟上限，必须为正数
+}
+
+// KeySetConfig 转换为 pkg/auth 使用的参数，避免 pkg 反向依赖 internal
+func (a AuthConfig) KeySetConfig() auth.KeySetConfig {
+	return auth.KeySetConfig{
+		Algorithm:      a.Algorithm,
+		Secret:         a.Secret,
+		PrivateKeyFile: a.PrivateKeyFile,
+		PublicKeyFiles: a.PublicKeyFiles,
+	}
+}
+
+// usesSharedSecret 是否使用 HS256 共享密钥
+func (a AuthConfig) usesSharedSecret() bool {
+	return a.Algorithm == "" || a.Algorithm == auth.AlgHS256
+}
+
+// IsProduction 是否运行在生产环境
+func (c *Config) IsProduction() bool {
+	return c.App.Env == "production"
+}
+
+// Validate 启动前的安全检查，配置不合格直接拒绝启动
+func (c *Config) Validate() error {
+	if c.Auth.TTL <= 0 {
+		return errors.New("auth.ttl must be positive")
+	}
+	if c.Auth.RefreshTTL < c.Auth.TTL {
+		return errors.New("auth.refresh_ttl must not be shorter than auth.ttl")
+	}
+	if c.App.Key == "" {
+		return errors.New("APP_KEY is required")
+	}
+	if c.IsProduction() && c.App.Key == DefaultAppKey {
+		return errors.New("APP_KEY must be set to a non-default value in production")
+	}
+	if c.Auth.VerificationTTL <= 0 {
+		return errors.New("auth.verification_ttl must be positive")
+	}
+	if c.Auth.MFAChallengeTTL <= 0 {
+		return errors.New("auth.mfa_challenge_ttl must be positive")
+	}
+	if c.Auth.PasswordResetTTL <= 0 {
+		return errors.New("auth.password_reset_ttl must be positive")
+	}
+	// 延迟期间请求一直占着 goroutine，必须封顶
+	if c.Auth.LoginThrottle.BaseDelay > 0 && c.Auth.LoginThrottle.MaxDelay <= 0 {
+		return errors.New("auth.login_throttle.max_delay must be positive")
+	}
+	if c.Auth.OIDC.Enabled && (c.Auth.OIDC.Issuer == "" || c.Auth.OIDC.ClientID == "" || c.Auth.OIDC.RedirectURL == "") {
+		return errors.New("OIDC_ISSUER, OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC login is enabled")
+	}
+	if !c.Auth.usesSharedSecret() {
+		if c.Auth.PrivateKeyFile == "" {
+			return errors.New("JWT_PRIVATE_KEY_FILE is required for " + c.Auth.Algorithm)
+		}
+		return nil
+	}
+	if c.IsProduction() && (c.Auth.Secret == "" || c.Auth.Secret == DefaultJWTSecret) {
+		return errors.New("JWT_SECRET must be set to a non-default value in production")
+	}
+	if c.Auth.Secret == "" {
+		return errors.New("JWT_SECRET is required")
+	}
+	return nil
+}
+
 func Load(configPath string) (*Config, error) {
 	// 2. 核心修复：手动加载 .env 文件
 	// 尝试加载根目录下的 .env，如果在生产环境没这个文件可以忽略错误
@@ -59,6 +259,43 @@ func Load(configPath string) (*Config, error) {
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
 
+	// 默认值：yaml 和 env 都没配置时兜底
+	v.SetDefault("auth.algorithm", "HS256")
+	v.SetDefault("auth.ttl", 15*time.Minute)
+	v.SetDefault("auth.refresh_ttl", 30*24*time.Hour)
+	v.SetDefault("auth.issuer", "go-artisan")
+	v.SetDefault("auth.clock_skew", 30*time.Second)
+	v.SetDefault("auth.password_reset_ttl", time.Hour)
+	v.SetDefault("auth.password_reset_cooldown", time.Minute)
+	v.SetDefault("auth.verification_ttl", time.Hour)
+	v.SetDefault("auth.verification_resend_cooldown", time.Minute)
+	v.SetDefault("auth.mfa_challenge_ttl", 5*time.Minute)
+	v.SetDefault("auth.login_throttle.max_account_attempts", 5)
+	v.SetDefault("auth.login_throttle.max_ip_attempts", 50)
+	v.SetDefault("auth.login_throttle.window", 15*time.Minute)
+	v.SetDefault("auth.login_throttle.lockout_duration", 15*time.Minute)
+	v.SetDefault("auth.login_throttle.base_delay", 200*time.Millisecond)
+	v.SetDefault("auth.login_throttle.max_delay", 3*time.Second)
+	v.SetDefault("auth.password_policy.min_length", 8)
+	v.SetDefault("auth.password_policy.max_length", 72)
+	v.SetDefault("auth.password_policy.require_upper", true)
+	v.SetDefault("auth.password_policy.require_lower", true)
+	v.SetDefault("auth.password_policy.require_digit", true)
+	v.SetDefault("auth.password_policy.require_symbol", false)
+	v.SetDefault("auth.password_policy.disallow_user_info", true)
+	v.SetDefault("auth.oidc.scopes", []string{"openid", "email", "profile"})
+	v.SetDefault("auth.oidc.auto_create", true)
+	v.SetDefault("app.url", "http://localhost:8080")
+	v.SetDefault("hash.driver", "argon2id")
+	v.SetDefault("hash.bcrypt_cost", 10)
+	v.SetDefault("hash.argon2.memory", 19456)
+	v.SetDefault("hash.argon2.time", 2)
+	v.SetDefault("hash.argon2.threads", 1)
+	v.SetDefault("mail.driver", "log")
+	v.SetDefault("mail.from", "GoArtisan <no-reply@go-artisan.local>")
+	v.SetDefault("mail.file_dir", "storage/mail")
+	v.SetDefault("mail.port", 587)
+
 	// 4. 读取 YAML 文件 (如果文件不存在，也不应该恐慌，可能全靠 ENV 配置)
 	if err := v.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
@@ -85,11 +322,69 @@ func Load(configPath string) (*Config, error) {
 	_ = v.BindEnv("redis.password", "REDIS_PASSWORD")
 	_ = v.BindEnv("redis.db", "REDIS_DB")
 
+	// 绑定 JWT
+	_ = v.BindEnv("auth.algorithm", "JWT_ALGORITHM")
+	_ = v.BindEnv("auth.secret", "JWT_SECRET")
+	_ = v.BindEnv("auth.private_key_file", "JWT_PRIVATE_KEY_FILE")
+	_ = v.BindEnv("auth.public_key_files", "JWT_PUBLIC_KEY_FILES") // 逗号分隔
+	_ = v.BindEnv("auth.ttl", "JWT_TTL")
+	_ = v.BindEnv("auth.refresh_ttl", "JWT_REFRESH_TTL")
+	_ = v.BindEnv("auth.issuer", "JWT_ISSUER")
+	_ = v.BindEnv("auth.audience", "JWT_AUDIENCE")
+	_ = v.BindEnv("auth.clock_skew", "JWT_CLOCK_SKEW")
+
+	// 绑定登录防爆破
+	_ = v.BindEnv("auth.login_throttle.max_account_attempts", "LOGIN_MAX_ACCOUNT_ATTEMPTS")
+	_ = v.BindEnv("auth.login_throttle.max_ip_attempts", "LOGIN_MAX_IP_ATTEMPTS")
+	_ = v.BindEnv("auth.login_throttle.lockout_duration", "LOGIN_LOCKOUT_DURATION")
+
+	// 绑定 OIDC 登录
+	_ = v.BindEnv("auth.oidc.enabled", "OIDC_ENABLED")
+	_ = v.BindEnv("auth.oidc.issuer", "OIDC_ISSUER")
+	_ = v.BindEnv("auth.oidc.client_id", "OIDC_CLIENT_ID")
+	_ = v.BindEnv("auth.oidc.client_secret", "OIDC_CLIENT_SECRET")
+	_ = v.BindEnv("auth.oidc.redirect_url", "OIDC_REDIRECT_URL")
+	_ = v.BindEnv("auth.oidc.allowed_domains", "OIDC_ALLOWED_DOMAINS") // 逗号分隔
+
+	// 绑定密码哈希
+	_ = v.BindEnv("hash.driver", "HASH_DRIVER")
+
+	// 绑定邮件
+	_ = v.BindEnv("app.url", "APP_URL")
+	_ = v.BindEnv("app.key", "APP_KEY")
+	_ = v.BindEnv("app.trusted_proxies", "TRUSTED_PROXIES") // 逗号分隔
+	_ = v.BindEnv("mail.driver", "MAIL_DRIVER")
+	_ = v.BindEnv("mail.from", "MAIL_FROM")
+	_ = v.BindEnv("mail.file_dir", "MAIL_FILE_DIR")
+	_ = v.BindEnv("mail.host", "MAIL_HOST")
+	_ = v.BindEnv("mail.port", "MAIL_PORT")
+	_ = v.BindEnv("mail.username", "MAIL_USERNAME")
+	_ = v.BindEnv("mail.password", "MAIL_PASSWORD")
+
 	// 7. 解析
 	var c Config
-	if err := v.Unmarshal(&c); err != nil {
+	hook := mapstructure.ComposeDecodeHookFunc(
+		secondsToDurationHook(), // 必须放在前面，否则 "86400" 会被 ParseDuration 拒绝
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+	)
+	if err := v.Unmarshal(&c, viper.DecodeHook(hook)); err != nil {
 		return nil, err
 	}
 
 	return &c, nil
 }
+
+// secondsToDurationHook 兼容 .env 里 JWT_TTL=86400 这种纯数字秒数写法
+func secondsToDurationHook() mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data any) (any, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(time.Duration(0)) {
+			return data, nil
+		}
+		secs, err := strconv.ParseInt(strings.TrimSpace(data.(string)), 10, 64)
+		if err != nil {
+			return data, nil
+		}
+		return time.Duration(secs) * time.Second, nil
+	}
+}
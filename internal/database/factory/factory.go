@@ -0,0 +1,141 @@
+// Package factory 模型工厂，批量生成测试数据 (类似 Laravel Model Factory)
+//
+//	users, err := factory.New[domain.User]().Count(50).Create(ctx)
+//	admin := factory.New[domain.User]().State(func(u *domain.User) { u.Name = "Admin" }).MakeOne()
+//
+// 每个模型的默认字段由 Define 注册 (见 user.go)，State 在此基础上覆盖部分字段
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"go-artisan/pkg/faker"
+
+	"gorm.io/gorm"
+)
+
+// createBatchSize 批量插入时每条 INSERT 的行数
+const createBatchSize = 100
+
+// ErrNoDB 没有通过 SetDB / Using 指定数据库
+var ErrNoDB = errors.New("factory: no database, call factory.SetDB or Using first")
+
+var (
+	mu          sync.RWMutex
+	definitions = map[reflect.Type]any{}
+	defaultDB   *gorm.DB
+)
+
+// Definition 生成一个模型的默认字段
+type Definition[T any] func(f *faker.Faker) T
+
+// Define 注册模型 T 的默认定义，重复注册时覆盖
+func Define[T any](def Definition[T]) {
+	mu.Lock()
+	defer mu.Unlock()
+	definitions[reflect.TypeFor[T]()] = def
+}
+
+// SetDB 设置 Create 默认使用的数据库 (db:seed 启动时注入)
+func SetDB(db *gorm.DB) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultDB = db
+}
+
+// Factory 一次生成的配置，方法都返回自身以便链式调用
+type Factory[T any] struct {
+	def    Definition[T]
+	faker  *faker.Faker
+	count  int
+	states []func(*T)
+	db     *gorm.DB
+}
+
+// New 模型 T 的工厂，T 没有注册定义时 panic (属于编程错误)
+func New[T any]() *Factory[T] {
+	mu.RLock()
+	def, ok := definitions[reflect.TypeFor[T]()]
+	mu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("factory: no definition registered for %s", reflect.TypeFor[T]()))
+	}
+	return &Factory[T]{def: def.(Definition[T]), faker: faker.New(), count: 1}
+}
+
+// Count 生成的数量，默认 1；负数按 0 处理
+func (f *Factory[T]) Count(n int) *Factory[T] {
+	f.count = max(n, 0)
+	return f
+}
+
+// State 在默认定义之上修改字段，多个 State 按添加顺序执行
+func (f *Factory[T]) State(fn func(*T)) *Factory[T] {
+	f.states = append(f.states, fn)
+	return f
+}
+
+// Seed 使用固定种子，生成可复现的数据
+func (f *Factory[T]) Seed(seed uint64) *Factory[T] {
+	f.faker = faker.NewSeeded(seed)
+	return f
+}
+
+// Using 使用指定的数据库 (例如测试中的 SQLite)，不影响 SetDB 的默认值
+func (f *Factory[T]) Using(db *gorm.DB) *Factory[T] {
+	f.db = db
+	return f
+}
+
+// Make 只生成不落库
+func (f *Factory[T]) Make() []*T {
+	items := make([]*T, f.count)
+	for i := range items {
+		item := f.def(f.faker)
+		for _, state := range f.states {
+			state(&item)
+		}
+		items[i] = &item
+	}
+	return items
+}
+
+// MakeOne 生成一个不落库的模型，忽略 Count
+func (f *Factory[T]) MakeOne() *T {
+	return f.Count(1).Make()[0]
+}
+
+// Create 生成并批量写入数据库，返回的模型带有数据库生成的主键
+func (f *Factory[T]) Create(ctx context.Context) ([]*T, error) {
+	db := f.db
+	if db == nil {
+		mu.RLock()
+		db = defaultDB
+		mu.RUnlock()
+	}
+	if db == nil {
+		return nil, ErrNoDB
+	}
+
+	items := f.Make()
+	if len(items) == 0 {
+		return items, nil
+	}
+	if err := db.WithContext(ctx).CreateInBatches(items, createBatchSize).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CreateOne 生成并写入一个模型，忽略 Count
+func (f *Factory[T]) CreateOne(ctx context.Context) (*T, error) {
+	items, err := f.Count(1).Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return items[0], nil
+}
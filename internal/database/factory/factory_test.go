@@ -0,0 +1,80 @@
+package factory_test
+
+import (
+	"context"
+	"testing"
+
+	"go-artisan/internal/database/factory"
+	"go-artisan/internal/domain"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	require.NoError(t, db.AutoMigrate(&domain.User{}))
+	return db
+}
+
+func TestFactory_Create(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+
+	users, err := factory.New[domain.User]().Using(db).Count(50).Create(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 50)
+
+	emails := map[string]bool{}
+	for _, u := range users {
+		assert.NotZero(t, u.ID)
+		assert.NotEmpty(t, u.Name)
+		assert.True(t, u.IsVerified())
+		emails[u.Email] = true
+	}
+	assert.Len(t, emails, 50, "邮箱不应重复")
+
+	var count int64
+	require.NoError(t, db.Model(&domain.User{}).Count(&count).Error)
+	assert.Equal(t, int64(50), count)
+}
+
+func TestFactory_State(t *testing.T) {
+	u := factory.New[domain.User]().
+		State(factory.Unverified).
+		State(func(u *domain.User) { u.Name = "Admin" }).
+		MakeOne()
+	assert.Equal(t, "Admin", u.Name)
+	assert.False(t, u.IsVerified())
+	assert.Zero(t, u.ID, "Make 不落库")
+
+	// 固定种子生成相同的数据
+	a := factory.New[domain.User]().Seed(42).Count(3).Make()
+	b := factory.New[domain.User]().Seed(42).Count(3).Make()
+	for i := range a {
+		assert.Equal(t, a[i].Email, b[i].Email)
+	}
+}
+
+func TestFactory_Errors(t *testing.T) {
+	factory.SetDB(nil)
+	_, err := factory.New[domain.User]().Create(context.Background())
+	assert.ErrorIs(t, err, factory.ErrNoDB)
+
+	assert.Panics(t, func() { factory.New[domain.APIKey]() })
+
+	// 负数按 0 处理，不会 panic
+	assert.Empty(t, factory.New[domain.User]().Count(-1).Make())
+	users, err := factory.New[domain.User]().Using(openTestDB(t)).Count(-5).Create(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
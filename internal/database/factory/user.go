@@ -0,0 +1,44 @@
+package factory
+
+import (
+	"sync"
+	"time"
+
+	"go-artisan/internal/domain"
+	"go-artisan/pkg/faker"
+	"go-artisan/pkg/hash"
+)
+
+// DefaultPassword 工厂生成的用户的明文密码
+const DefaultPassword = "password"
+
+// passwordHash 只计算一次，避免批量生成时每个用户都做一次慢哈希
+// 使用 bcrypt 是因为任何哈希驱动都能校验它 (首次登录后按当前驱动自动升级)
+var passwordHash = sync.OnceValue(func() string {
+	hasher, err := hash.NewBcrypt(0)
+	if err != nil {
+		panic(err)
+	}
+	hashed, err := hasher.Hash(DefaultPassword)
+	if err != nil {
+		panic(err)
+	}
+	return hashed
+})
+
+func init() {
+	Define(func(f *faker.Faker) domain.User {
+		verifiedAt := f.PastTime(90 * 24 * time.Hour)
+		return domain.User{
+			Name:            f.Name(),
+			Email:           f.Email(),
+			Password:        passwordHash(),
+			EmailVerifiedAt: &verifiedAt,
+		}
+	})
+}
+
+// Unverified 邮箱未验证的用户
+func Unverified(u *domain.User) {
+	u.EmailVerifiedAt = nil
+}
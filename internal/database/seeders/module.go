@@ -0,0 +1,15 @@
+package seeders
+
+import (
+	"go-artisan/internal/database/factory"
+
+	"go.uber.org/fx"
+)
+
+// Module 注册全部 Seeder，只由 artisan db:seed 加载，Server 不会用到
+var Module = fx.Options(
+	fx.Provide(NewRunner),
+	fx.Invoke(factory.SetDB), // 工厂的 Create 使用同一个数据库连接
+
+	fx.Provide(AsSeeder(NewUserSeeder)),
+)
@@ -0,0 +1,91 @@
+// Package seeders 数据库填充 (类似 Laravel Seeder)，通过 artisan db:seed 执行
+//
+// 新的 Seeder 用 artisan make:seeder 生成，然后在 module.go 中用 AsSeeder 注册
+package seeders
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// Seeder 向数据库填充一类数据，应当可以重复执行 (已存在的数据跳过)
+// db:seed --class 按类型名选择，如 UserSeeder
+type Seeder interface {
+	Run(ctx context.Context) error
+}
+
+// AsSeeder 把 Seeder 的构造函数注册到 seeders 组
+func AsSeeder(constructor any) any {
+	return fx.Annotate(constructor, fx.As(new(Seeder)), fx.ResultTags(`group:"seeders"`))
+}
+
+// Name Seeder 的类型名
+func Name(s Seeder) string {
+	t := reflect.TypeOf(s)
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Runner 按名称执行已注册的 Seeder
+type Runner struct {
+	seeders []Seeder
+	logger  *slog.Logger
+}
+
+type RunnerParams struct {
+	fx.In
+
+	Seeders []Seeder `group:"seeders"`
+	Logger  *slog.Logger
+}
+
+// NewRunner fx 组内的顺序不固定，这里按名称排序；有先后依赖的 Seeder 应当注入并调用被依赖的 Seeder
+func NewRunner(p RunnerParams) *Runner {
+	seeders := slices.Clone(p.Seeders)
+	slices.SortFunc(seeders, func(a, b Seeder) int { return strings.Compare(Name(a), Name(b)) })
+	return &Runner{seeders: seeders, logger: p.Logger}
+}
+
+// Names 已注册的 Seeder 名称
+func (r *Runner) Names() []string {
+	names := make([]string, len(r.seeders))
+	for i, s := range r.seeders {
+		names[i] = Name(s)
+	}
+	return names
+}
+
+// Run 执行名为 class 的 Seeder，class 为空时执行全部
+func (r *Runner) Run(ctx context.Context, class string) error {
+	if class == "" {
+		for _, s := range r.seeders {
+			if err := r.run(ctx, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, s := range r.seeders {
+		if Name(s) == class {
+			return r.run(ctx, s)
+		}
+	}
+	return fmt.Errorf("seeder %q not found (registered: %s)", class, strings.Join(r.Names(), ", "))
+}
+
+func (r *Runner) run(ctx context.Context, s Seeder) error {
+	r.logger.Info("Seeding", "seeder", Name(s))
+	if err := s.Run(ctx); err != nil {
+		return fmt.Errorf("%s: %w", Name(s), err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package seeders_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"go-artisan/internal/database/seeders"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct{ ran []string }
+
+type PostSeeder struct{ r *recorder }
+
+func (s *PostSeeder) Run(context.Context) error {
+	s.r.ran = append(s.r.ran, "PostSeeder")
+	return nil
+}
+
+type CommentSeeder struct{ r *recorder }
+
+func (s *CommentSeeder) Run(context.Context) error {
+	s.r.ran = append(s.r.ran, "CommentSeeder")
+	return nil
+}
+
+func TestRunner(t *testing.T) {
+	r := &recorder{}
+	runner := seeders.NewRunner(seeders.RunnerParams{
+		Seeders: []seeders.Seeder{&PostSeeder{r}, &CommentSeeder{r}},
+		Logger:  slog.Default(),
+	})
+	ctx := context.Background()
+
+	require.NoError(t, runner.Run(ctx, ""))
+	assert.Equal(t, []string{"CommentSeeder", "PostSeeder"}, r.ran, "按名称顺序执行")
+
+	r.ran = nil
+	require.NoError(t, runner.Run(ctx, "PostSeeder"))
+	assert.Equal(t, []string{"PostSeeder"}, r.ran)
+
+	assert.ErrorContains(t, runner.Run(ctx, "UserSeeder"), "registered: CommentSeeder, PostSeeder")
+}
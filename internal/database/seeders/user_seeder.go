@@ -0,0 +1,100 @@
+package seeders
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/database/factory"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/hash"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// AdminEmail 管理员账户
+	AdminEmail = "admin@example.com"
+	// AdminPasswordEnv 管理员密码的环境变量，未设置时使用 factory.DefaultPassword；生产环境必须设置
+	AdminPasswordEnv = "SEED_ADMIN_PASSWORD"
+	// seedUserCount 填充后 users 表至少有这么多用户
+	seedUserCount = 50
+)
+
+// ErrAdminPasswordRequired 生产环境创建管理员时没有提供密码，不能使用人尽皆知的默认密码
+var ErrAdminPasswordRequired = errors.New("seeders: " + AdminPasswordEnv + " is required to create the admin account in production")
+
+// UserSeeder 一个管理员 + 若干随机用户 (随机用户只在非生产环境生成)
+type UserSeeder struct {
+	db          *gorm.DB
+	users       domain.UserRepository
+	permissions *service.PermissionService
+	hasher      hash.Hasher
+	config      *config.Config
+}
+
+func NewUserSeeder(db *gorm.DB, users domain.UserRepository, permissions *service.PermissionService, hasher hash.Hasher, cfg *config.Config) *UserSeeder {
+	return &UserSeeder{db: db, users: users, permissions: permissions, hasher: hasher, config: cfg}
+}
+
+func (s *UserSeeder) Run(ctx context.Context) error {
+	admin, err := s.admin(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := s.permissions.AssignRole(service.RoleAssignment{Subject: service.UserSubject(admin.ID), Role: "admin"}); err != nil {
+		return err
+	}
+
+	// 随机用户的密码都是 factory.DefaultPassword，不能出现在生产环境
+	if s.config.IsProduction() {
+		return nil
+	}
+	// 只补足差额，重复执行不会无限增长
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&domain.User{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if missing := seedUserCount - int(count); missing > 0 {
+		_, err = factory.New[domain.User]().Count(missing).Create(ctx)
+	}
+	return err
+}
+
+// admin 查找或创建管理员
+// 管理员被注销 (软删除) 后邮箱仍占用唯一索引，不能重新创建，直接恢复原账户
+func (s *UserSeeder) admin(ctx context.Context) (*domain.User, error) {
+	var admin domain.User
+	err := s.db.WithContext(ctx).Unscoped().Where("email = ?", AdminEmail).First(&admin).Error
+	if err == nil {
+		if admin.DeletedAt.Valid {
+			if _, err := s.users.Restore(ctx, admin.ID); err != nil {
+				return nil, err
+			}
+		}
+		return &admin, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	password := os.Getenv(AdminPasswordEnv)
+	if password == "" && s.config.IsProduction() {
+		return nil, ErrAdminPasswordRequired
+	}
+	var hashed string
+	if password != "" {
+		if hashed, err = s.hasher.Hash(password); err != nil {
+			return nil, err
+		}
+	}
+	return factory.New[domain.User]().State(func(u *domain.User) {
+		u.Name = "Admin"
+		u.Email = AdminEmail
+		if hashed != "" {
+			u.Password = hashed
+		}
+	}).CreateOne(ctx)
+}
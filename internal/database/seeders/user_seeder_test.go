@@ -0,0 +1,88 @@
+package seeders_test
+
+import (
+	"context"
+	"testing"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/database/factory"
+	"go-artisan/internal/database/seeders"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/provider"
+	"go-artisan/internal/repository"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/hash"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestUserSeeder(t *testing.T, env string) (*seeders.UserSeeder, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	require.NoError(t, db.AutoMigrate(&domain.User{}))
+	factory.SetDB(db)
+	t.Cleanup(func() { factory.SetDB(nil) })
+
+	e, err := casbin.NewSyncedEnforcer(provider.NewCasbinModel())
+	require.NoError(t, err)
+	hasher, err := hash.NewBcrypt(4)
+	require.NoError(t, err)
+	cfg := &config.Config{App: config.AppConfig{Env: env}}
+	return seeders.NewUserSeeder(db, repository.NewUserRepo(db), service.NewPermissionService(e), hasher, cfg), db
+}
+
+func countUsers(t *testing.T, db *gorm.DB) int64 {
+	var count int64
+	require.NoError(t, db.Model(&domain.User{}).Count(&count).Error)
+	return count
+}
+
+func TestUserSeeder(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("重复执行不会重复创建", func(t *testing.T) {
+		seeder, db := newTestUserSeeder(t, "local")
+		require.NoError(t, seeder.Run(ctx))
+		require.NoError(t, seeder.Run(ctx))
+		assert.Equal(t, int64(50), countUsers(t, db))
+	})
+
+	t.Run("管理员被注销后恢复原账户", func(t *testing.T) {
+		seeder, db := newTestUserSeeder(t, "local")
+		require.NoError(t, seeder.Run(ctx))
+		require.NoError(t, db.Where("email = ?", seeders.AdminEmail).Delete(&domain.User{}).Error)
+
+		require.NoError(t, seeder.Run(ctx))
+		var admin domain.User
+		require.NoError(t, db.Where("email = ?", seeders.AdminEmail).First(&admin).Error)
+		assert.Equal(t, uint(1), admin.ID)
+	})
+
+	t.Run("生产环境必须提供管理员密码，且不生成随机用户", func(t *testing.T) {
+		seeder, db := newTestUserSeeder(t, "production")
+		t.Setenv(seeders.AdminPasswordEnv, "")
+		assert.ErrorIs(t, seeder.Run(ctx), seeders.ErrAdminPasswordRequired)
+		assert.Zero(t, countUsers(t, db))
+
+		t.Setenv(seeders.AdminPasswordEnv, "s3cret-admin-pass")
+		require.NoError(t, seeder.Run(ctx))
+		assert.Equal(t, int64(1), countUsers(t, db))
+
+		var admin domain.User
+		require.NoError(t, db.First(&admin).Error)
+		hasher, err := hash.NewBcrypt(4)
+		require.NoError(t, err)
+		ok, err := hasher.Check("s3cret-admin-pass", admin.Password)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// APIKey 对应 api_keys 表：给 CI、脚本等机器客户端使用的长期凭证
+// 完整的 Key 只在创建时返回一次，库里保存 SHA-256；Prefix 明文保存，方便用户在列表中辨认
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:32;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"serializer:json" json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at"` // 为空表示永不过期
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Expired 是否已过期
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+// APIKeyRepository API Key 仓储
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	FindByHash(ctx context.Context, hash string) (*APIKey, error)
+	ListByUserID(ctx context.Context, userID uint) ([]APIKey, error)
+	// Delete 只能删除自己的 Key，不存在时返回 false
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+	TouchLastUsed(ctx context.Context, id uint, at time.Time) error
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,84 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/domain/two_factor.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/domain/two_factor.go -destination=internal/domain/mocks/two_factor_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRecoveryCodeRepository is a mock of RecoveryCodeRepository interface.
+type MockRecoveryCodeRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRecoveryCodeRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRecoveryCodeRepositoryMockRecorder is the mock recorder for MockRecoveryCodeRepository.
+type MockRecoveryCodeRepositoryMockRecorder struct {
+	mock *MockRecoveryCodeRepository
+}
+
+// NewMockRecoveryCodeRepository creates a new mock instance.
+func NewMockRecoveryCodeRepository(ctrl *gomock.Controller) *MockRecoveryCodeRepository {
+	mock := &MockRecoveryCodeRepository{ctrl: ctrl}
+	mock.recorder = &MockRecoveryCodeRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRecoveryCodeRepository) EXPECT() *MockRecoveryCodeRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Consume mocks base method.
+func (m *MockRecoveryCodeRepository) Consume(ctx context.Context, userID uint, hash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", ctx, userID, hash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Consume indicates an expected call of Consume.
+func (mr *MockRecoveryCodeRepositoryMockRecorder) Consume(ctx, userID, hash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockRecoveryCodeRepository)(nil).Consume), ctx, userID, hash)
+}
+
+// DeleteByUserID mocks base method.
+func (m *MockRecoveryCodeRepository) DeleteByUserID(ctx context.Context, userID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByUserID", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByUserID indicates an expected call of DeleteByUserID.
+func (mr *MockRecoveryCodeRepositoryMockRecorder) DeleteByUserID(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByUserID", reflect.TypeOf((*MockRecoveryCodeRepository)(nil).DeleteByUserID), ctx, userID)
+}
+
+// ReplaceAll mocks base method.
+func (m *MockRecoveryCodeRepository) ReplaceAll(ctx context.Context, userID uint, hashes []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceAll", ctx, userID, hashes)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceAll indicates an expected call of ReplaceAll.
+func (mr *MockRecoveryCodeRepositoryMockRecorder) ReplaceAll(ctx, userID, hashes any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceAll", reflect.TypeOf((*MockRecoveryCodeRepository)(nil).ReplaceAll), ctx, userID, hashes)
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// OAuthClient 对应 oauth_clients 表：接入 OAuth2 的第三方应用
+// 机密客户端 (有后端) 持有 client_secret，库里只存 SHA-256；公开客户端 (SPA/移动端) 没有 secret，必须使用 PKCE
+type OAuthClient struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	ClientID     string    `gorm:"size:64;not null;uniqueIndex" json:"client_id"`
+	SecretHash   string    `gorm:"size:64" json:"-"`
+	Name         string    `gorm:"size:100;not null" json:"name"`
+	RedirectURIs []string  `gorm:"serializer:json" json:"redirect_uris"`
+	GrantTypes   []string  `gorm:"serializer:json" json:"grant_types"`
+	Scopes       []string  `gorm:"serializer:json" json:"scopes"` // 允许申请的 scope
+	Confidential bool      `gorm:"not null" json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// AllowsGrant 是否允许使用该授权类型
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// AllowsRedirect redirect_uri 必须与注册值完全一致 (不做前缀匹配，防止开放重定向)
+func (c *OAuthClient) AllowsRedirect(uri string) bool {
+	return slices.Contains(c.RedirectURIs, uri)
+}
+
+// OAuthClientRepository OAuth 客户端仓储
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *OAuthClient) error
+	FindByClientID(ctx context.Context, clientID string) (*OAuthClient, error)
+	List(ctx context.Context) ([]OAuthClient, error)
+	// Delete 不存在时返回 false
+	Delete(ctx context.Context, clientID string) (bool, error)
+}
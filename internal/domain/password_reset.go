@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// PasswordResetToken 对应 password_reset_tokens 表
+// 只保存 Token 的 SHA-256，明文只出现在发给用户的邮件里
+type PasswordResetToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // 非空表示已使用
+	CreatedAt time.Time
+}
+
+// PasswordResetRepository 重置密码 Token 仓储
+type PasswordResetRepository interface {
+	Create(ctx context.Context, token *PasswordResetToken) error
+	FindByHash(ctx context.Context, hash string) (*PasswordResetToken, error)
+	// MarkUsed 原子地标记为已使用，Token 已被使用过时返回 false (防止并发重复使用)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
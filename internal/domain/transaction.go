@@ -0,0 +1,11 @@
+package domain
+
+import "context"
+
+// TxManager 跨多个仓储的事务 (Unit of Work)
+//
+// fn 收到的 ctx 携带事务，仓储用它执行的 SQL 都在同一事务中；fn 返回错误 (或 panic) 时回滚
+// 嵌套调用使用 SAVEPOINT，内层失败只回滚内层。遇到死锁时整个事务会重试，fn 中不要有不可重复的副作用 (发邮件等放到事务之后)
+type TxManager interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// RecoveryCode 对应 two_factor_recovery_codes 表，丢失验证器时用于登录
+// 只保存 SHA-256，每个恢复码只能使用一次
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (RecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
+
+// RecoveryCodeRepository 恢复码仓储
+type RecoveryCodeRepository interface {
+	// ReplaceAll 用新的一批恢复码替换用户现有的全部恢复码
+	ReplaceAll(ctx context.Context, userID uint, hashes []string) error
+	// Consume 原子地使用一个恢复码，不存在或已使用时返回 false
+	Consume(ctx context.Context, userID uint, hash string) (bool, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
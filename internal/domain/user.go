@@ -1,7 +1,10 @@
 package domain
 
 import (
+	"context"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // User 对应数据库 users 表
@@ -12,11 +15,40 @@ type User struct {
 	Password  string    `gorm:"size:255;not null" json:"-"` // JSON 序列化时不返回密码
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at"` // 为空表示邮箱未验证
+
+	TwoFactorSecret      string     `gorm:"size:255" json:"-"`       // 加密后的 TOTP 密钥
+	TwoFactorConfirmedAt *time.Time `json:"two_factor_confirmed_at"` // 为空表示未开启 (或尚未完成确认)
+
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"` // 软删除，查询默认排除已注销的账户
+	AnonymizedAt *time.Time     `json:"-"`              // 清除时已匿名化 (仍被其他表引用而无法删除)，不能再恢复
+}
+
+// IsVerified 邮箱是否已验证
+func (u *User) IsVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// TwoFactorEnabled 是否已开启两步验证 (扫码后还需输入一次验证码确认才算开启)
+func (u *User) TwoFactorEnabled() bool {
+	return u.TwoFactorConfirmedAt != nil && u.TwoFactorSecret != ""
 }
 
 // UserRepo 接口定义 (为了测试 Mock，这里必须用 Interface)
 type UserRepository interface {
-	Create(user *User) error
-	FindByEmail(email string) (*User, error)
-	FindByID(id uint) (*User, error) // 👈 新增接口定义
+	Create(ctx context.Context, user *User) error
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByID(ctx context.Context, id uint) (*User, error) // 👈 新增接口定义
+	Update(ctx context.Context, user *User) error
+	// Delete 软删除，不存在 (或已删除) 时返回 false
+	Delete(ctx context.Context, id uint) (bool, error)
+	// Restore 恢复软删除的用户，不存在、未删除或已匿名化时返回 false
+	Restore(ctx context.Context, id uint) (bool, error)
+	// ForceDelete 物理删除 (包括已软删除的行)，关联的认证数据由外键级联删除
+	ForceDelete(ctx context.Context, id uint) (bool, error)
+	// FindDeletedBefore 按 ID 升序返回 before 之前软删除、尚未匿名化且 ID 大于 afterID 的用户，用于分批清除
+	FindDeletedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]User, error)
+	// Anonymize 抹去用户的个人信息并删除其认证数据，保留一行无法关联到本人的记录，并记录匿名化时间
+	Anonymize(ctx context.Context, id uint) error
 }
@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// UserIdentity 对应 user_identities 表：本地用户与外部身份提供方 (OIDC) 账号的绑定
+// 以 (provider, subject) 识别外部账号，IdP 侧修改邮箱后仍能找到同一个本地用户
+type UserIdentity struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	Provider  string `gorm:"size:255;not null"` // IdP 的 issuer
+	Subject   string `gorm:"size:255;not null"` // ID Token 中的 sub
+	Email     string `gorm:"size:255"`          // 绑定时 IdP 返回的邮箱，仅供排查
+	CreatedAt time.Time
+}
+
+// UserIdentityRepository 外部身份绑定仓储
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *UserIdentity) error
+	FindBySubject(ctx context.Context, provider, subject string) (*UserIdentity, error)
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/response"
+	myvalidator "go-artisan/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandler 个人 API Key 管理
+type APIKeyHandler struct {
+	svc    *service.APIKeyService
+	logger *slog.Logger
+}
+
+type createAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes"`                                             // 不填表示没有任何 scope，只能访问不要求 scope 的接口
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=3650"` // 不填表示永不过期
+}
+
+func NewAPIKeyHandler(svc *service.APIKeyService, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc, logger: logger}
+}
+
+// Index 列出当前用户的 API Key (GET /api/user/api-keys)
+func (h *APIKeyHandler) Index(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+
+	keys, err := h.svc.List(c.Request.Context(), uid)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, keys)
+}
+
+// Store 创建 API Key (POST /api/user/api-keys)，完整 Key 只在响应中出现这一次
+func (h *APIKeyHandler) Store(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	dto := service.CreateAPIKeyDTO{Name: req.Name, Scopes: req.Scopes}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		dto.ExpiresAt = &expiresAt
+	}
+
+	key, err := h.svc.Create(c.Request.Context(), uid, dto)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("API key created", "user_id", uid, "prefix", key.Prefix)
+	response.Success(c, key)
+}
+
+// Destroy 吊销 API Key (DELETE /api/user/api-keys/:id)
+func (h *APIKeyHandler) Destroy(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(service.ErrAPIKeyNotFound)
+		return
+	}
+
+	if err := h.svc.Revoke(c.Request.Context(), uid, uint(id)); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("API key revoked", "user_id", uid, "id", id)
+	response.Success(c, nil)
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/response"
+	myvalidator "go-artisan/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthHandler OAuth2 授权服务器端点与客户端管理
+// /oauth/token、/oauth/introspect、/oauth/revoke 按 RFC 直接输出 JSON，不包 Response
+type OAuthHandler struct {
+	svc    *service.OAuthService
+	logger *slog.Logger
+}
+
+type approveRequest struct {
+	service.AuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+type registerClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"` // 不填默认 authorization_code + refresh_token
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"` // 有后端能保管 secret 的应用为 true，SPA/移动端为 false
+}
+
+func NewOAuthHandler(svc *service.OAuthService, logger *slog.Logger) *OAuthHandler {
+	return &OAuthHandler{svc: svc, logger: logger}
+}
+
+// Authorize 校验授权请求并返回授权确认页所需信息 (GET /oauth/authorize)
+//
+// 这是给第一方前端用的 JSON 授权确认 API，不是浏览器直接访问的前端通道页面：
+// 客户端把浏览器重定向到前端的授权确认页，前端带上用户的 Bearer Token 调用本接口展示 prompt，
+// 用户确认后调用 POST /oauth/authorize，再由前端跳转到返回的 redirect_to
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req service.AuthorizeRequest
+	_ = c.ShouldBindQuery(&req)
+
+	prompt, err := h.svc.Authorize(c.Request.Context(), req)
+	if err != nil {
+		h.authorizeError(c, err)
+		return
+	}
+	response.Success(c, prompt)
+}
+
+// Approve 用户确认或拒绝授权 (POST /oauth/authorize)，由前端跳转到返回的 redirect_to
+func (h *OAuthHandler) Approve(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	var req approveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	redirectTo, err := h.svc.Approve(c.Request.Context(), uid, req.AuthorizeRequest, req.Approve)
+	if err != nil {
+		h.authorizeError(c, err)
+		return
+	}
+
+	h.logger.Info("OAuth authorization decided", "user_id", uid, "client_id", req.ClientID, "approved", req.Approve)
+	response.Success(c, gin.H{"redirect_to": redirectTo})
+}
+
+// Token 令牌端点 (POST /oauth/token)
+func (h *OAuthHandler) Token(c *gin.Context) {
+	clientID, clientSecret := clientCredentials(c)
+	token, err := h.svc.Token(c.Request.Context(), service.TokenRequest{
+		GrantType:    c.PostForm("grant_type"),
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		CodeVerifier: c.PostForm("code_verifier"),
+		RefreshToken: c.PostForm("refresh_token"),
+		Scope:        c.PostForm("scope"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	})
+	if err != nil {
+		h.tokenError(c, "OAuth token request failed", err)
+		return
+	}
+
+	// RFC 6749 5.1：Token 响应不允许缓存
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, token)
+}
+
+// Introspect 令牌自省 (POST /oauth/introspect，RFC 7662)
+func (h *OAuthHandler) Introspect(c *gin.Context) {
+	clientID, clientSecret := clientCredentials(c)
+	result, err := h.svc.Introspect(c.Request.Context(), clientID, clientSecret, c.PostForm("token"), c.PostForm("token_type_hint"))
+	if err != nil {
+		h.tokenError(c, "OAuth introspection failed", err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, result)
+}
+
+// Revoke 吊销令牌 (POST /oauth/revoke，RFC 7009)
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	clientID, clientSecret := clientCredentials(c)
+	if err := h.svc.Revoke(c.Request.Context(), clientID, clientSecret, c.PostForm("token"), c.PostForm("token_type_hint")); err != nil {
+		h.tokenError(c, "OAuth revocation failed", err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// Clients 列出客户端 (GET /api/admin/oauth/clients)
+func (h *OAuthHandler) Clients(c *gin.Context) {
+	clients, err := h.svc.ListClients(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	response.Success(c, clients)
+}
+
+// StoreClient 注册客户端 (POST /api/admin/oauth/clients)，client_secret 只在响应中出现这一次
+func (h *OAuthHandler) StoreClient(c *gin.Context) {
+	var req registerClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	client, err := h.svc.RegisterClient(c.Request.Context(), service.RegisterClientDTO{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		GrantTypes:   req.GrantTypes,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("OAuth client registered", "client_id", client.ClientID, "name", client.Name)
+	response.Success(c, client)
+}
+
+// DestroyClient 删除客户端 (DELETE /api/admin/oauth/clients/:client_id)
+func (h *OAuthHandler) DestroyClient(c *gin.Context) {
+	clientID := c.Param("client_id")
+	if err := h.svc.DeleteClient(c.Request.Context(), clientID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("OAuth client deleted", "client_id", clientID)
+	response.Success(c, nil)
+}
+
+// authorizeError 可重定向的错误把回调地址交给前端，其余错误直接展示给用户
+func (h *OAuthHandler) authorizeError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		_ = c.Error(err)
+		return
+	}
+
+	data := gin.H{"error": oauthErr.Code}
+	if redirectTo := oauthErr.RedirectURL(); redirectTo != "" {
+		data["redirect_to"] = redirectTo
+	}
+	c.JSON(http.StatusBadRequest, response.Response{
+		Code:    http.StatusBadRequest,
+		Message: oauthErr.Description,
+		Data:    data,
+	})
+}
+
+// tokenError 按 RFC 6749 5.2 输出 {error, error_description}
+func (h *OAuthHandler) tokenError(c *gin.Context, msg string, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		h.logger.Error(msg, "err", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == service.OAuthInvalidClient {
+		status = http.StatusUnauthorized
+		if _, _, ok := c.Request.BasicAuth(); ok {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, oauthErr)
+}
+
+// clientCredentials 客户端认证：优先 HTTP Basic (RFC 6749 2.3.1，用户名密码先经过 form 编码)，其次表单字段
+func clientCredentials(c *gin.Context) (string, string) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		if decoded, err := url.QueryUnescape(id); err == nil {
+			id = decoded
+		}
+		if decoded, err := url.QueryUnescape(secret); err == nil {
+			secret = decoded
+		}
+		return id, secret
+	}
+	return c.PostForm("client_id"), c.PostForm("client_secret")
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie 把 state 绑定到发起登录的浏览器，防止攻击者诱导受害者登录攻击者的账户 (登录 CSRF)
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/auth/oidc"
+)
+
+// OIDCHandler 企业 IdP 单点登录
+type OIDCHandler struct {
+	svc    *service.OIDCService
+	config *config.Config
+	logger *slog.Logger
+}
+
+func NewOIDCHandler(svc *service.OIDCService, cfg *config.Config, logger *slog.Logger) *OIDCHandler {
+	return &OIDCHandler{svc: svc, config: cfg, logger: logger}
+}
+
+// Redirect 跳转到 IdP 登录页 (GET /api/auth/oidc/redirect)
+func (h *OIDCHandler) Redirect(c *gin.Context) {
+	if !h.svc.Enabled() {
+		_ = c.Error(service.ErrOIDCDisabled)
+		return
+	}
+
+	authURL, state, err := h.svc.AuthURL(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.setStateCookie(c, state, 600)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback IdP 登录完成后的回调 (GET /api/auth/oidc/callback)，响应与 /api/login 一致
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if !h.svc.Enabled() {
+		_ = c.Error(service.ErrOIDCDisabled)
+		return
+	}
+
+	// 用户在 IdP 拒绝授权或 IdP 出错
+	if idpErr := c.Query("error"); idpErr != "" {
+		h.logger.Warn("OIDC provider returned error", "error", idpErr, "description", c.Query("error_description"))
+		_ = c.Error(service.ErrOIDCRejected)
+		return
+	}
+
+	state := c.Query("state")
+	cookie, _ := c.Cookie(oidcStateCookie)
+	h.setStateCookie(c, "", -1)
+	if state == "" || cookie != state {
+		_ = c.Error(service.ErrInvalidOIDCState)
+		return
+	}
+
+	res, err := h.svc.Callback(c.Request.Context(), state, c.Query("code"))
+	if err != nil {
+		// 客户端只看到 "oidc login failed"，与 IdP 交互失败的具体原因记在日志里
+		if errors.Is(err, service.ErrOIDCLoginFailed) {
+			h.logger.Warn("OIDC login failed", "err", err)
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	if res.User != nil {
+		h.logger.Info("OIDC login", "user_id", res.User.ID)
+	}
+	response.Success(c, res)
+}
+
+func (h *OIDCHandler) setStateCookie(c *gin.Context, value string, maxAge int) {
+	secure := strings.HasPrefix(h.config.Auth.OIDC.RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode) // IdP 回调是跨站的顶层 GET 跳转，Lax 仍会携带
+	c.SetCookie(oidcStateCookie, value, maxAge, oidcStateCookiePath, "", secure, true)
+}
@@ -25,3 +25,8 @@ func (h *OrderHandler) Index(c *gin.Context) {
 	h.logger.Info("Accessing Order Index")
 	response.Success(c, gin.H{"module": "Order", "action": "index"})
 }
+
+// Show 示例方法 (对应 GET /api/orders/:id，Casbin 策略可写成 /api/orders/:id)
+func (h *OrderHandler) Show(c *gin.Context) {
+	response.Success(c, gin.H{"module": "Order", "action": "show", "id": c.Param("id")})
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"go-artisan/internal/service"
+	"go-artisan/pkg/response"
+
+	"log/slog"
+
+	myvalidator "go-artisan/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionHandler 策略管理接口 (仅 role:admin 可访问)
+type PermissionHandler struct {
+	svc    *service.PermissionService
+	logger *slog.Logger
+}
+
+func NewPermissionHandler(svc *service.PermissionService, logger *slog.Logger) *PermissionHandler {
+	return &PermissionHandler{svc: svc, logger: logger}
+}
+
+// Policies GET /api/admin/policies
+func (h *PermissionHandler) Policies(c *gin.Context) {
+	policies, err := h.svc.Policies()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	response.Success(c, policies)
+}
+
+// AddPolicy POST /api/admin/policies
+func (h *PermissionHandler) AddPolicy(c *gin.Context) {
+	var req service.Policy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	added, err := h.svc.AddPolicy(req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if !added {
+		response.Error(c, 409, "policy already exists")
+		return
+	}
+
+	h.logger.Info("Policy added", "sub", req.Subject, "obj", req.Object, "act", req.Action)
+	response.Success(c, req)
+}
+
+// RemovePolicy DELETE /api/admin/policies
+func (h *PermissionHandler) RemovePolicy(c *gin.Context) {
+	var req service.Policy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	removed, err := h.svc.RemovePolicy(req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if !removed {
+		response.Error(c, 404, "policy not found")
+		return
+	}
+
+	h.logger.Info("Policy removed", "sub", req.Subject, "obj", req.Object, "act", req.Action)
+	response.Success(c, nil)
+}
+
+// Roles GET /api/admin/roles
+func (h *PermissionHandler) Roles(c *gin.Context) {
+	assignments, err := h.svc.RoleAssignments()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	response.Success(c, assignments)
+}
+
+// AssignRole POST /api/admin/roles
+func (h *PermissionHandler) AssignRole(c *gin.Context) {
+	var req service.RoleAssignment
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	added, err := h.svc.AssignRole(req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if !added {
+		response.Error(c, 409, "role already assigned")
+		return
+	}
+
+	h.logger.Info("Role assigned", "sub", req.Subject, "role", req.Role)
+	response.Success(c, req)
+}
+
+// UnassignRole DELETE /api/admin/roles
+func (h *PermissionHandler) UnassignRole(c *gin.Context) {
+	var req service.RoleAssignment
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	removed, err := h.svc.UnassignRole(req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if !removed {
+		response.Error(c, 404, "role assignment not found")
+		return
+	}
+
+	h.logger.Info("Role unassigned", "sub", req.Subject, "role", req.Role)
+	response.Success(c, nil)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"log/slog"
+
+	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/response"
+	myvalidator "go-artisan/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler 两步验证的开启/确认/关闭 (登录第二步在 UserHandler.LoginTwoFactor)
+type TwoFactorHandler struct {
+	svc    *service.TwoFactorService
+	logger *slog.Logger
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+func NewTwoFactorHandler(svc *service.TwoFactorService, logger *slog.Logger) *TwoFactorHandler {
+	return &TwoFactorHandler{svc: svc, logger: logger}
+}
+
+// Enable 生成 TOTP 密钥 (POST /api/user/two-factor)，返回 otpauth:// URI 供前端生成二维码
+func (h *TwoFactorHandler) Enable(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+
+	setup, err := h.svc.Enable(c.Request.Context(), uid)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, setup)
+}
+
+// Confirm 提交第一个验证码完成开启 (POST /api/user/two-factor/confirm)，恢复码只在这里展示一次
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	codes, err := h.svc.Confirm(c.Request.Context(), uid, req.Code)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("Two-factor authentication enabled", "user_id", uid)
+	response.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// Disable 关闭两步验证 (DELETE /api/user/two-factor)
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	if err := h.svc.Disable(c.Request.Context(), uid, req.Code); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Warn("Two-factor authentication disabled", "user_id", uid, "ip", c.ClientIP())
+	response.Success(c, nil)
+}
+
+// RecoveryCodes 重新生成恢复码 (POST /api/user/two-factor/recovery-codes)，旧恢复码全部作废
+func (h *TwoFactorHandler) RecoveryCodes(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	var req twoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), uid, req.Code)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, gin.H{"recovery_codes": codes})
+}
@@ -1,9 +1,13 @@
 package handler
 
 import (
-	// 引入新包 (起别名避免冲突)
+	"errors"
+	"io"
+	"strconv"
 
 	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/errs"
 	"go-artisan/pkg/response"
 
 	"log/slog"
@@ -14,8 +18,12 @@ import (
 )
 
 type UserHandler struct {
-	svc    *service.UserService
-	logger *slog.Logger
+	svc      *service.UserService
+	tokens   *service.TokenService
+	resets   *service.PasswordResetService
+	verify   *service.EmailVerificationService
+	accounts *service.AccountService
+	logger   *slog.Logger
 }
 
 // 增加结构体定义
@@ -24,14 +32,61 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-func NewUserHandler(svc *service.UserService, logger *slog.Logger) *UserHandler {
-	return &UserHandler{svc: svc, logger: logger}
+type twoFactorLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // 6 位验证码或恢复码
+}
+
+type unlockLoginRequest struct {
+	Email string `json:"email" binding:"required_without=IP,omitempty,email"`
+	IP    string `json:"ip" binding:"required_without=Email,omitempty,ip"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,password"`
+}
+
+type updateProfileRequest struct {
+	Name            string `json:"name" binding:"omitempty,max=255"`
+	Email           string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string `json:"current_password" binding:"required_with=Email"` // 修改邮箱时必填
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	Password        string `json:"password" binding:"required,password"`
+}
+
+// deactivateRequest 密码和两步验证码二选一；都不提供时要求当前会话是刚刚登录的
+type deactivateRequest struct {
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+func NewUserHandler(
+	svc *service.UserService,
+	tokens *service.TokenService,
+	resets *service.PasswordResetService,
+	verify *service.EmailVerificationService,
+	accounts *service.AccountService,
+	logger *slog.Logger,
+) *UserHandler {
+	return &UserHandler{svc: svc, tokens: tokens, resets: resets, verify: verify, accounts: accounts, logger: logger}
 }
 
 type registerRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required,password"` // 规则见 auth.password_policy
 }
 
 func (h *UserHandler) Register(c *gin.Context) {
@@ -47,21 +102,24 @@ func (h *UserHandler) Register(c *gin.Context) {
 	}
 
 	// 2. 调用服务
-	user, err := h.svc.Register(service.RegisterDTO{
+	user, err := h.svc.Register(c.Request.Context(), service.RegisterDTO{
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: req.Password,
 	})
 
 	if err != nil {
-		// 这里的 err 是业务错误（如：邮箱已存在）
-		// 在 V2 中我们会定义统一的 ErrEmailExists 业务错误码
-		h.logger.Error("Register service error", "err", err)
-		response.Error(c, 500, err.Error())
+		// 业务错误 (如 ErrEmailTaken) 由 ErrorHandler 按业务码渲染
+		_ = c.Error(err)
 		return
 	}
 
-	// 3. 成功返回
+	// 3. 发送验证邮件 (失败不影响注册，用户可以登录后重发)
+	if err := h.verify.Send(c.Request.Context(), user); err != nil {
+		h.logger.Warn("Send verification email failed", "user_id", user.ID, "err", err)
+	}
+
+	// 4. 成功返回
 	// 注意：user 里面可能包含一些你不想要额外字段，V2 里我们会做 DTO->VO 转换
 	response.Success(c, user)
 }
@@ -73,16 +131,275 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	res, err := h.svc.Login(service.LoginDTO{
+	res, err := h.svc.Login(c.Request.Context(), service.LoginDTO{
 		Email:    req.Email,
 		Password: req.Password,
+		IP:       c.ClientIP(),
+	})
+
+	if err != nil {
+		h.logger.Warn("Login failed", "email", req.Email, "ip", c.ClientIP(), "error", err)
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// LoginTwoFactor 两步登录第二步 (POST /api/login/two-factor)
+func (h *UserHandler) LoginTwoFactor(c *gin.Context) {
+	var req twoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	res, err := h.svc.LoginTwoFactor(c.Request.Context(), service.TwoFactorLoginDTO{
+		MFAToken: req.MFAToken,
+		Code:     req.Code,
+		IP:       c.ClientIP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMFAToken) || errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			h.logger.Warn("Two-factor login failed", "ip", c.ClientIP(), "error", err)
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, res)
+}
+
+// Refresh 用 Refresh Token 换取新的 Token 对 (旧 Refresh Token 随即作废)
+func (h *UserHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	pair, err := h.tokens.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrRefreshTokenReused) {
+			// 重放意味着 Token 可能泄露，需要重点关注
+			h.logger.Warn("Refresh token reuse detected, token family revoked", "ip", c.ClientIP())
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, pair)
+}
+
+// Logout 登出当前会话 (当前 Access Token 与对应的 Refresh Token 全部失效)
+func (h *UserHandler) Logout(c *gin.Context) {
+	claims := auth.MustCurrentUser(c).Claims
+	if claims == nil {
+		_ = c.Error(errs.ErrUnauthorized)
+		return
+	}
+
+	if err := h.tokens.Revoke(c.Request.Context(), claims); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// LogoutAll 在所有设备上登出 (此前签发的全部 Token 与 API Key 失效)
+func (h *UserHandler) LogoutAll(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+
+	if err := h.svc.RevokeCredentials(c.Request.Context(), uid); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ForgotPassword 发送重置密码邮件 (POST /api/password/forgot)
+// 无论邮箱是否存在都返回成功，防止枚举注册邮箱
+func (h *UserHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	err := h.resets.Forgot(c.Request.Context(), service.ForgotPasswordDTO{Email: req.Email, IP: c.ClientIP()})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "If that email address is registered, a password reset link has been sent"})
+}
+
+// ResetPassword 使用邮件中的 Token 设置新密码 (POST /api/password/reset)
+// 成功后该用户此前签发的所有 Token 失效，需要重新登录
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	err := h.resets.Reset(c.Request.Context(), service.ResetPasswordDTO{
+		Token:    req.Token,
+		Password: req.Password,
 	})
+	if err != nil {
+		// 请求里没有邮箱和姓名，"不能包含用户信息" 只能在 Service 里校验 (ErrWeakPassword)
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// VerifyEmail 验证邮箱 (GET /api/email/verify，来自邮件中的签名链接)
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	user, err := h.verify.Verify(c.Request.Context(), c.Request.URL.Query())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, user)
+}
+
+// ResendVerification 重发验证邮件 (POST /api/email/verification-notification)
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+
+	if err := h.verify.Resend(c.Request.Context(), uid); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Verification link sent"})
+}
+
+// UnlockLogin 管理员解除账户/IP 的登录锁定 (POST /api/admin/login-lockouts/unlock)
+func (h *UserHandler) UnlockLogin(c *gin.Context) {
+	var req unlockLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	if err := h.svc.UnlockLogin(c.Request.Context(), req.Email, req.IP); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("Login lockout cleared", "email", req.Email, "ip", req.IP, "by", auth.MustCurrentUser(c).UserID)
+	response.Success(c, nil)
+}
+
+// Profile 当前用户资料 (GET /api/user/profile)，走 Redis 缓存
+func (h *UserHandler) Profile(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
 
+	user, err := h.svc.GetUserProfile(c.Request.Context(), uid)
 	if err != nil {
-		h.logger.Warn("Login failed", "email", req.Email, "error", err)
-		response.Error(c, 401, err.Error())
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, user)
+}
+
+// UpdateProfile 修改姓名/邮箱 (PATCH /api/user/profile)，修改邮箱后需要重新验证
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
 		return
 	}
 
+	user, err := h.svc.UpdateProfile(c.Request.Context(), uid, service.UpdateProfileDTO{
+		Name:            req.Name,
+		Email:           req.Email,
+		CurrentPassword: req.CurrentPassword,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// 新邮箱需要重新验证 (失败不影响修改，用户可以稍后重发)
+	if req.Email != "" && !user.IsVerified() {
+		if err := h.verify.Send(c.Request.Context(), user); err != nil {
+			h.logger.Warn("Send verification email failed", "user_id", user.ID, "err", err)
+		}
+	}
+
+	response.Success(c, user)
+}
+
+// ChangePassword 修改密码 (PUT /api/user/password)，其他设备全部登出，返回当前会话的新 Token
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	uid := auth.MustCurrentUser(c).UserID
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	res, err := h.svc.ChangePassword(c.Request.Context(), uid, service.ChangePasswordDTO{
+		CurrentPassword: req.CurrentPassword,
+		Password:        req.Password,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("Password changed", "user_id", uid)
 	response.Success(c, res)
 }
+
+// Deactivate 注销当前账户 (DELETE /api/user)，保留期内可由管理员恢复
+func (h *UserHandler) Deactivate(c *gin.Context) {
+	user := auth.MustCurrentUser(c)
+	uid := user.UserID
+	var req deactivateRequest
+	// 依靠最近登录确认身份时可以不带请求体
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.ValidationError(c, myvalidator.Translate(err))
+		return
+	}
+
+	dto := service.DeactivateDTO{CurrentPassword: req.CurrentPassword, Code: req.Code}
+	if user.Claims != nil && user.Claims.AuthTime != nil {
+		dto.AuthTime = user.Claims.AuthTime.Time
+	}
+	if err := h.accounts.Deactivate(c.Request.Context(), uid, dto); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("Account deactivated", "user_id", uid)
+	response.Success(c, nil)
+}
+
+// Restore 管理员恢复已注销的账户 (POST /api/admin/users/:id/restore)
+func (h *UserHandler) Restore(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		_ = c.Error(service.ErrUserNotFound)
+		return
+	}
+
+	if err := h.accounts.Restore(c.Request.Context(), uint(id)); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.logger.Info("Account restored", "user_id", id, "by", auth.MustCurrentUser(c).UserID)
+	response.Success(c, nil)
+}
@@ -1,24 +1,40 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
+	"go-artisan/internal/service"
 	"go-artisan/pkg/auth"
 	"go-artisan/pkg/response"
 
 	"github.com/gin-gonic/gin"
 )
 
-const ContextUserIDKey = "userID"
-
-// AuthMiddleware 接收配置中的 Secret
-// 由于 Middleware 初始化在 Router 构造时，可以通过传参注入
-func AuthMiddleware() gin.HandlerFunc {
-	// TODO: 从配置中获取 Secret，目前写死必须与 Login 保持一致
-	secret := "KeepItSecretKeepItSafe!GoArtisanKey"
+// Handler 中请使用 auth.CurrentUser / auth.MustCurrentUser 读取当前用户
+const (
+	ContextUserIDKey = auth.ContextUserIDKey
+	ContextClaimsKey = auth.ContextClaimsKey
+	ContextAPIKeyKey = "apiKey" // *domain.APIKey (仅 API Key 认证时存在)
+	ContextScopesKey = auth.ContextScopesKey
+)
 
+// AuthMiddleware 同时接受 JWT 与 API Key，两种方式都把 userID 写入上下文
+//
+//	Authorization: Bearer <jwt>
+//	Authorization: Bearer ga_xxxxxxxx_xxxx   (API Key，按前缀区分)
+//	X-API-Key: ga_xxxxxxxx_xxxx
+//
+// 由于 Middleware 初始化在 Router 构造时，可以通过传参注入 (TokenService 由 Fx 注入 Router)
+func AuthMiddleware(tokens *service.TokenService, apiKeys *service.APIKeyService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1. 获取 Header
+		// 1. 机器客户端习惯使用的 X-API-Key
+		if key := c.GetHeader("X-API-Key"); key != "" {
+			authenticateAPIKey(c, apiKeys, key)
+			return
+		}
+
+		// 2. 获取 Header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			response.Error(c, 401, "Authorization header required")
@@ -26,25 +42,74 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 2. 解析 Bearer Token
+		// 3. 解析 Bearer Token
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			response.Error(c, 401, "Invalid authorization format")
 			c.Abort()
 			return
 		}
+		if service.IsAPIKey(parts[1]) {
+			authenticateAPIKey(c, apiKeys, parts[1])
+			return
+		}
 
-		// 3. 校验 Token
-		claims, err := auth.ParseToken(parts[1], secret)
+		// 4. 校验 Token (包含是否已登出)
+		claims, err := tokens.Authenticate(c.Request.Context(), parts[1])
 		if err != nil {
-			response.Error(c, 401, "Invalid or expired token")
+			switch {
+			case errors.Is(err, service.ErrTokenRevoked):
+				response.Error(c, 401, "Token has been revoked")
+			case errors.Is(err, service.ErrInvalidToken):
+				response.Error(c, 401, "Invalid or expired token")
+			default:
+				response.Error(c, 500, "Failed to verify token")
+			}
+			c.Abort()
+			return
+		}
+
+		// 5. client_credentials 签发的 Token 不代表任何用户，不能访问用户接口
+		if claims.UserID == 0 {
+			response.Error(c, 403, "Client credentials tokens cannot access user endpoints")
 			c.Abort()
 			return
 		}
 
-		// 4. 将 ID 注入上下文，后续 Controller 可以通过 c.Get("userID") 获取
-		c.Set(ContextUserIDKey, claims.UserID)
+		// 6. 将当前用户注入上下文，后续 Controller 可以通过 auth.CurrentUser(c) 获取
+		auth.SetCurrentUser(c, auth.Principal{UserID: claims.UserID, Scopes: claims.Scopes(), Claims: claims})
+
+		c.Next()
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeys *service.APIKeyService, plain string) {
+	key, err := apiKeys.Authenticate(c.Request.Context(), plain)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAPIKey) {
+			response.Error(c, 401, "Invalid or expired API key")
+		} else {
+			response.Error(c, 500, "Failed to verify API key")
+		}
+		c.Abort()
+		return
+	}
+
+	auth.SetCurrentUser(c, auth.Principal{UserID: key.UserID, Scopes: key.Scopes})
+	c.Set(ContextAPIKeyKey, key)
+	c.Next()
+}
 
+// RequireSession 只允许第一方 JWT 登录态访问 (必须挂在 AuthMiddleware 之后)
+// 用于管理 API Key、OAuth2 授权等敏感操作，防止泄露的 API Key 或第三方 Token 给自己续命/提权
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := auth.CurrentUser(c)
+		if !ok || user.Claims == nil || user.Claims.ClientID != "" {
+			response.Error(c, 403, "This action requires a login session, API keys and OAuth tokens are not accepted")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -1,30 +1,32 @@
 package middleware
 
 import (
-	"strconv"
-
+	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
 	"go-artisan/pkg/response"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 )
 
-func CasbinMiddleware(e *casbin.Enforcer) gin.HandlerFunc {
+// CasbinMiddleware 必须挂在 AuthMiddleware 之后 (依赖它写入的 userID)
+func CasbinMiddleware(e *casbin.SyncedEnforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. 获取请求的 URL 和 Method
+		// 策略使用 keyMatch2，/api/orders/:id 可以匹配这里的 /api/orders/42
 		obj := c.Request.URL.Path
 		act := c.Request.Method
 
 		// 2. 获取当前用户 (从 AuthMiddleware 设置的 Context 里取)
-		uid, exists := c.Get(ContextUserIDKey) // 假设你在 auth.go 里 set 的 key 是这个
+		user, exists := auth.CurrentUser(c)
 		if !exists {
 			response.Error(c, 401, "Unauthenticated")
 			c.Abort()
 			return
 		}
 
-		// 假设我们的 Subject 是 "user:1", "user:2" 这种格式
-		sub := "user:" + strconv.Itoa(int(uid.(uint)))
+		// Subject 是 "user:1", "user:2" 这种格式
+		sub := service.UserSubject(user.UserID)
 
 		// 3. 检查权限
 		ok, err := e.Enforce(sub, obj, act)
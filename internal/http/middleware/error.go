@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler 统一渲染 Handler 通过 c.Error(err) 交出的错误 (见 pkg/errs)
+// Handler 已经写了响应时不再处理；非预期错误记录完整错误链，客户端只会看到 500
+func ErrorHandler(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		if errs.KindOf(err) == errs.KindInternal {
+			logger.Error("Unhandled error",
+				"trace_id", c.GetString("trace_id"),
+				"method", c.Request.Method,
+				"path", c.FullPath(),
+				"err", err,
+			)
+		}
+		response.Fail(c, err)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-artisan/internal/http/middleware"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       int
+		message    string
+		retryAfter string
+	}{
+		{"业务错误", errs.NotFound(40401, "user not found"), http.StatusNotFound, 40401, "user not found", ""},
+		{"被包装的业务错误", fmt.Errorf("load user: %w", errs.Conflict(40901, "email already taken")), http.StatusConflict, 40901, "email already taken", ""},
+		{"通用错误使用 HTTP 状态码 * 100", errs.ErrForbidden, http.StatusForbidden, 40300, "forbidden", ""},
+		{"限流带 Retry-After (向上取整)", errs.RateLimited(42902, "account locked").RetryIn(1500 * time.Millisecond), http.StatusTooManyRequests, 42902, "account locked", "2"},
+		{"非预期错误不暴露细节", errors.New("dial tcp 10.0.0.5:3306: connection refused"), http.StatusInternalServerError, errs.CodeInternal, "internal server error", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(middleware.ErrorHandler(slog.New(slog.DiscardHandler)))
+			r.GET("/", func(c *gin.Context) { _ = c.Error(tt.err) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.retryAfter, w.Header().Get("Retry-After"))
+
+			var body response.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Code)
+			assert.Equal(t, tt.message, body.Message)
+		})
+	}
+
+	t.Run("Handler 已经写了响应时不再渲染", func(t *testing.T) {
+		r := gin.New()
+		r.Use(middleware.ErrorHandler(slog.New(slog.DiscardHandler)))
+		r.GET("/", func(c *gin.Context) {
+			_ = c.Error(errors.New("logged only"))
+			response.Success(c, nil)
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope 要求 Token / API Key 拥有全部指定的 scope (必须挂在 AuthMiddleware 之后)
+//
+//	orders.POST("", middleware.RequireScope("orders:write"), orderHandler.Store)
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, _ := auth.CurrentUser(c)
+
+		if missing := auth.MissingScopes(user.Scopes, scopes...); len(missing) > 0 {
+			response.InsufficientScope(c, missing)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedMiddleware 拦截邮箱未验证的账户 (类似 Laravel 的 verified 中间件)
+// 必须挂在 AuthMiddleware 之后；用户资料走 Redis 缓存，不会每次请求都查库
+func VerifiedMiddleware(users *service.UserService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current, exists := auth.CurrentUser(c)
+		if !exists {
+			response.Error(c, 401, "Unauthenticated")
+			c.Abort()
+			return
+		}
+
+		user, err := users.GetUserProfile(c.Request.Context(), current.UserID)
+		if err != nil {
+			response.Error(c, 401, "Unauthenticated")
+			c.Abort()
+			return
+		}
+
+		if !user.IsVerified() {
+			response.Error(c, 403, "Your email address is not verified")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package router
+
+import (
+	"go-artisan/internal/http/middleware"
+	"go-artisan/internal/service"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// GroupOption 路由组选项 (类似 Laravel Route::middleware(['auth:api', 'can']))
+// 选项按传入顺序挂载中间件，所以 WithAuth 必须写在 WithVerified / WithScope / WithCasbin 前面
+type GroupOption func(g *gin.RouterGroup)
+
+// WithAuth 要求登录 (JWT 或 API Key)
+func WithAuth(tokens *service.TokenService, apiKeys *service.APIKeyService) GroupOption {
+	return func(g *gin.RouterGroup) {
+		g.Use(middleware.AuthMiddleware(tokens, apiKeys))
+	}
+}
+
+// WithSessionOnly 只接受 JWT 登录态，拒绝 API Key
+func WithSessionOnly() GroupOption {
+	return func(g *gin.RouterGroup) {
+		g.Use(middleware.RequireSession())
+	}
+}
+
+// WithVerified 要求邮箱已验证
+func WithVerified(users *service.UserService) GroupOption {
+	return func(g *gin.RouterGroup) {
+		g.Use(middleware.VerifiedMiddleware(users))
+	}
+}
+
+// WithScope 要求 Token / API Key 拥有指定 scope
+func WithScope(scopes ...string) GroupOption {
+	return func(g *gin.RouterGroup) {
+		g.Use(middleware.RequireScope(scopes...))
+	}
+}
+
+// WithCasbin 要求当前用户通过 Casbin 鉴权
+func WithCasbin(e *casbin.SyncedEnforcer) GroupOption {
+	return func(g *gin.RouterGroup) {
+		g.Use(middleware.CasbinMiddleware(e))
+	}
+}
+
+// newGroup 创建路由组并依次应用选项
+func newGroup(parent gin.IRouter, path string, opts ...GroupOption) *gin.RouterGroup {
+	g := parent.Group(path)
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
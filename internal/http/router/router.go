@@ -1,13 +1,18 @@
 package router
 
 import (
+	"fmt"
+	"net/http"
+
 	"go-artisan/internal/config"
 	"go-artisan/internal/http/handler"
 	"go-artisan/internal/http/middleware"
+	"go-artisan/internal/service"
 	"go-artisan/pkg/response"
 
 	"log/slog"
 
+	"github.com/casbin/casbin/v2"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/fx"
 )
@@ -26,7 +31,17 @@ func NewRouter(
 	logger *slog.Logger,
 	welcomeHandler *handler.WelcomeHandler,
 	userHandler *handler.UserHandler, // <-- 新增注入参数
-) *gin.Engine {
+	orderHandler *handler.OrderHandler,
+	permissionHandler *handler.PermissionHandler,
+	twoFactorHandler *handler.TwoFactorHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	oauthHandler *handler.OAuthHandler,
+	oidcHandler *handler.OIDCHandler,
+	tokens *service.TokenService,
+	apiKeys *service.APIKeyService,
+	users *service.UserService,
+	enforcer *casbin.SyncedEnforcer,
+) (*gin.Engine, error) {
 
 	// 设置运行模式
 	if cfg.App.Env == "production" {
@@ -34,11 +49,36 @@ func NewRouter(
 	}
 
 	r := gin.New()
+	// gin 默认信任任何来源的 X-Forwarded-For，必须显式配置
+	if err := r.SetTrustedProxies(cfg.App.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid app.trusted_proxies: %w", err)
+	}
 
 	// 1. 全局中间件
 	r.Use(gin.Recovery())
 	r.Use(middleware.LoggerMiddleware(logger)) // 自定义结构化日志中间件
 	r.Use(middleware.VersionMiddleware())      // 👈 新增
+	r.Use(middleware.ErrorHandler(logger))     // 统一渲染 c.Error(err)
+
+	// 公钥发布 (其他服务据此验证我们签发的 JWT，按标准直接输出 JWKS，不包 Response)
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, tokens.JWKS())
+	})
+
+	// OAuth2 授权服务器：令牌相关端点靠客户端认证，授权确认只接受第一方登录态
+	oauth := r.Group("/oauth")
+	{
+		oauth.POST("/token", oauthHandler.Token)
+		oauth.POST("/introspect", oauthHandler.Introspect)
+		oauth.POST("/revoke", oauthHandler.Revoke)
+	}
+	// 授权确认是给第一方前端调用的 JSON API (Bearer)，浏览器应当先跳转到前端的确认页
+	consent := newGroup(r, "/oauth/authorize", WithAuth(tokens, apiKeys), WithSessionOnly())
+	{
+		consent.GET("", oauthHandler.Authorize)
+		consent.POST("", oauthHandler.Approve)
+	}
 
 	// 公开路由
 	public := r.Group("/api")
@@ -48,21 +88,67 @@ func NewRouter(
 		})
 		public.POST("/register", userHandler.Register)
 		public.POST("/login", userHandler.Login) // 👈 新增
+		public.POST("/login/two-factor", userHandler.LoginTwoFactor)
+		public.POST("/token/refresh", userHandler.Refresh)
+		public.POST("/password/forgot", userHandler.ForgotPassword)
+		public.POST("/password/reset", userHandler.ResetPassword)
+		public.GET("/email/verify", userHandler.VerifyEmail) // 邮件中的签名链接
+		public.GET("/auth/oidc/redirect", oidcHandler.Redirect)
+		public.GET("/auth/oidc/callback", oidcHandler.Callback)
 	}
 
 	// 保护路由 (类似 Laravel Route::middleware('auth:api'))
-	protected := r.Group("/api")
-	protected.Use(middleware.AuthMiddleware())
+	protected := newGroup(r, "/api", WithAuth(tokens, apiKeys))
 	{
-		protected.GET("/user/profile", func(c *gin.Context) {
-			// 获取中间件塞入的 userID
-			uid, _ := c.Get("userID")
-			response.Success(c, gin.H{
-				"message": "You are accessing protected data",
-				"your_id": uid,
-			})
-		})
+		// 登出针对的是 JWT 会话，API Key 请通过 DELETE /api/user/api-keys/:id 吊销
+		protected.POST("/logout", middleware.RequireSession(), userHandler.Logout)
+		protected.POST("/logout/all", middleware.RequireSession(), userHandler.LogoutAll)
+		protected.POST("/email/verification-notification", userHandler.ResendVerification)
+
+		// 两步验证同样属于账户安全操作，泄露的 API Key 不能用来开关或换取恢复码
+		protected.POST("/user/two-factor", middleware.RequireSession(), twoFactorHandler.Enable)
+		protected.POST("/user/two-factor/confirm", middleware.RequireSession(), twoFactorHandler.Confirm)
+		protected.DELETE("/user/two-factor", middleware.RequireSession(), twoFactorHandler.Disable)
+		protected.POST("/user/two-factor/recovery-codes", middleware.RequireSession(), twoFactorHandler.RecoveryCodes)
+
+		protected.GET("/user/profile", userHandler.Profile)
+		// 修改邮箱/密码属于账户安全操作，只接受登录态
+		protected.PATCH("/user/profile", middleware.RequireSession(), userHandler.UpdateProfile)
+		protected.PUT("/user/password", middleware.RequireSession(), userHandler.ChangePassword)
+		protected.DELETE("/user", middleware.RequireSession(), userHandler.Deactivate)
+	}
+
+	// API Key 管理：只接受 JWT 登录态，泄露的 API Key 不能用来创建新 Key
+	apiKeyRoutes := newGroup(r, "/api/user/api-keys", WithAuth(tokens, apiKeys), WithSessionOnly())
+	{
+		apiKeyRoutes.GET("", apiKeyHandler.Index)
+		apiKeyRoutes.POST("", apiKeyHandler.Store)
+		apiKeyRoutes.DELETE("/:id", apiKeyHandler.Destroy)
+	}
+
+	// 鉴权路由 (登录 + 邮箱已验证 + Casbin，类似 Laravel Route::middleware(['auth:api', 'verified', 'can']))
+	// scope 限制 Token / API Key 能做什么，Casbin 限制用户本人能做什么，两者都要满足
+	orders := newGroup(r, "/api/orders", WithAuth(tokens, apiKeys), WithVerified(users), WithCasbin(enforcer))
+	{
+		orders.GET("", middleware.RequireScope("orders:read"), orderHandler.Index)
+		orders.GET("/:id", middleware.RequireScope("orders:read"), orderHandler.Show)
+	}
+
+	// 策略管理接口：内置策略 role:admin -> /api/admin/* 保证只有管理员可访问
+	admin := newGroup(r, "/api/admin", WithAuth(tokens, apiKeys), WithScope("admin"), WithCasbin(enforcer))
+	{
+		admin.GET("/policies", permissionHandler.Policies)
+		admin.POST("/policies", permissionHandler.AddPolicy)
+		admin.DELETE("/policies", permissionHandler.RemovePolicy)
+		admin.GET("/roles", permissionHandler.Roles)
+		admin.POST("/roles", permissionHandler.AssignRole)
+		admin.DELETE("/roles", permissionHandler.UnassignRole)
+		admin.POST("/login-lockouts/unlock", userHandler.UnlockLogin)
+		admin.POST("/users/:id/restore", userHandler.Restore)
+		admin.GET("/oauth/clients", oauthHandler.Clients)
+		admin.POST("/oauth/clients", oauthHandler.StoreClient)
+		admin.DELETE("/oauth/clients/:client_id", oauthHandler.DestroyClient)
 	}
 
-	return r
+	return r, nil
 }
@@ -9,29 +9,42 @@ import (
 	"gorm.io/gorm"
 )
 
-// Casbin Enforcer 是线程安全的
-func NewCasbinEnforcer(db *gorm.DB) (*casbin.Enforcer, error) {
-	// 1. 初始化 Gorm 适配器 (它会自动在库里创建 casbin_rule 表)
-	adapter, err := gormadapter.NewAdapterByDB(db)
-	if err != nil {
-		return nil, err
-	}
+// AdminPolicy 内置策略：role:admin 可以访问全部 /api/admin/* 管理接口
+var AdminPolicy = []string{"role:admin", "/api/admin/*", "*"}
 
-	// 2. 加载模型 (RBAC)
-	// 这里使用代码定义的模型，避免依赖文件。
+// NewCasbinModel 返回代码定义的 RBAC 模型
+// Server 与 artisan CLI 共用这一份定义，保证两边的鉴权结果永远一致
+func NewCasbinModel() model.Model {
 	// r: sub(谁), obj(资源), act(动作)
 	// p: 定义策略
 	// g: 定义角色组
 	// m: 匹配逻辑
+	//    keyMatch2 支持路径参数，策略写 /api/orders/:id 即可匹配 /api/orders/42
+	//    act 为 * 时匹配任意 HTTP 方法
 	m := model.NewModel()
 	m.AddDef("r", "r", "sub, obj, act")
 	m.AddDef("p", "p", "sub, obj, act")
 	m.AddDef("g", "g", "_, _")
 	m.AddDef("e", "e", "some(where (p.eft == allow))")
-	m.AddDef("m", "m", "g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act")
+	m.AddDef("m", "m", `g(r.sub, p.sub) && keyMatch2(r.obj, p.obj) && (r.act == p.act || p.act == "*")`)
+	return m
+}
+
+// NewCasbinEnforcer 使用 SyncedEnforcer：策略会在运行时通过管理接口修改，必须加锁
+// watcher 负责多实例间的策略同步，为 nil 时只在本进程生效 (如 Redis 不可用的 CLI)
+func NewCasbinEnforcer(db *gorm.DB, watcher *CasbinWatcher) (*casbin.SyncedEnforcer, error) {
+	// 1. 初始化 Gorm 适配器 (它会自动在库里创建 casbin_rule 表)
+	adapter, err := gormadapter.NewAdapterByDB(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 加载模型 (RBAC)
+	// 这里使用代码定义的模型，避免依赖文件。
+	m := NewCasbinModel()
 
 	// 3. 创建 Enforcer
-	e, err := casbin.NewEnforcer(m, adapter)
+	e, err := casbin.NewSyncedEnforcer(m, adapter)
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +54,18 @@ func NewCasbinEnforcer(db *gorm.DB) (*casbin.Enforcer, error) {
 		return nil, err
 	}
 
+	// 5. 确保管理员策略存在，否则没有人能调用策略管理接口
+	if _, err := e.AddPolicy(AdminPolicy); err != nil {
+		return nil, err
+	}
+
+	// 6. 挂载 Watcher：本节点的变更广播出去，其他节点的变更增量应用进来
+	if watcher != nil {
+		if err := watcher.Attach(e); err != nil {
+			return nil, err
+		}
+	}
+
 	log.Println("✅ Casbin initialized successfully")
 	return e, nil
 }
@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+)
+
+// CasbinPolicyChannel 策略变更广播频道
+const CasbinPolicyChannel = "casbin:policy:changed"
+
+// 变更类型
+const (
+	policyOpAdd            = "add"
+	policyOpRemove         = "remove"
+	policyOpRemoveFiltered = "remove_filtered"
+	policyOpReload         = "reload" // 无法增量表达的变更 (SavePolicy 等)，收到后全量 LoadPolicy
+)
+
+// policyMessage 在 Redis 上传输的变更消息
+type policyMessage struct {
+	Instance    string     `json:"instance"` // 发送方实例 ID，用于忽略自己发出的消息
+	Op          string     `json:"op"`
+	Sec         string     `json:"sec,omitempty"`
+	Ptype       string     `json:"ptype,omitempty"`
+	Rules       [][]string `json:"rules,omitempty"`
+	FieldIndex  int        `json:"field_index,omitempty"`
+	FieldValues []string   `json:"field_values,omitempty"`
+}
+
+// CasbinWatcher 基于 Redis Pub/Sub 的 Casbin Watcher
+// 任意节点 (包括 artisan CLI) 修改策略后广播变更，其他节点增量更新内存中的策略，无需重启
+type CasbinWatcher struct {
+	rdb      *redis.Client
+	logger   *slog.Logger
+	instance string
+
+	mu       sync.Mutex
+	callback func(string)
+	pubsub   *redis.PubSub
+}
+
+var _ persist.WatcherEx = (*CasbinWatcher)(nil)
+
+func NewCasbinWatcher(rdb *redis.Client, logger *slog.Logger) *CasbinWatcher {
+	return &CasbinWatcher{rdb: rdb, logger: logger, instance: uuid.NewString()}
+}
+
+// RegisterCasbinWatcher 把订阅挂到 Fx 生命周期上：启动时订阅，关闭时退订
+func RegisterCasbinWatcher(lc fx.Lifecycle, w *CasbinWatcher) {
+	lc.Append(fx.Hook{
+		OnStart: w.Start,
+		OnStop: func(ctx context.Context) error {
+			w.Close()
+			return nil
+		},
+	})
+}
+
+// Attach 绑定 Enforcer，收到其他节点的变更后增量应用到本节点
+func (w *CasbinWatcher) Attach(e *casbin.SyncedEnforcer) error {
+	// SetWatcher 会注册一个默认的全量 LoadPolicy 回调，这里覆盖成增量更新
+	if err := e.SetWatcher(w); err != nil {
+		return err
+	}
+	return w.SetUpdateCallback(func(payload string) {
+		w.apply(e, payload)
+	})
+}
+
+// Start 订阅变更频道 (只有 Server 需要，CLI 只负责发布)
+func (w *CasbinWatcher) Start(ctx context.Context) error {
+	pubsub := w.rdb.Subscribe(context.Background(), CasbinPolicyChannel)
+	// 等待订阅确认，Redis 不可用时让启动失败而不是静默丢消息
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return fmt.Errorf("failed to subscribe %s: %w", CasbinPolicyChannel, err)
+	}
+
+	w.mu.Lock()
+	w.pubsub = pubsub
+	w.mu.Unlock()
+
+	go func() {
+		for msg := range pubsub.Channel() {
+			w.mu.Lock()
+			cb := w.callback
+			w.mu.Unlock()
+			if cb != nil {
+				cb(msg.Payload)
+			}
+		}
+	}()
+	return nil
+}
+
+// SetUpdateCallback 实现 persist.Watcher
+func (w *CasbinWatcher) SetUpdateCallback(cb func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = cb
+	return nil
+}
+
+// Update 实现 persist.Watcher：通知其他节点全量重载
+func (w *CasbinWatcher) Update() error {
+	return w.publish(policyMessage{Op: policyOpReload})
+}
+
+// Close 实现 persist.Watcher
+func (w *CasbinWatcher) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pubsub != nil {
+		_ = w.pubsub.Close()
+		w.pubsub = nil
+	}
+}
+
+func (w *CasbinWatcher) UpdateForAddPolicy(sec, ptype string, params ...string) error {
+	return w.publish(policyMessage{Op: policyOpAdd, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+func (w *CasbinWatcher) UpdateForRemovePolicy(sec, ptype string, params ...string) error {
+	return w.publish(policyMessage{Op: policyOpRemove, Sec: sec, Ptype: ptype, Rules: [][]string{params}})
+}
+
+func (w *CasbinWatcher) UpdateForRemoveFilteredPolicy(sec, ptype string, fieldIndex int, fieldValues ...string) error {
+	return w.publish(policyMessage{
+		Op: policyOpRemoveFiltered, Sec: sec, Ptype: ptype, FieldIndex: fieldIndex, FieldValues: fieldValues,
+	})
+}
+
+func (w *CasbinWatcher) UpdateForSavePolicy(model.Model) error {
+	return w.publish(policyMessage{Op: policyOpReload})
+}
+
+func (w *CasbinWatcher) UpdateForAddPolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(policyMessage{Op: policyOpAdd, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+func (w *CasbinWatcher) UpdateForRemovePolicies(sec string, ptype string, rules ...[]string) error {
+	return w.publish(policyMessage{Op: policyOpRemove, Sec: sec, Ptype: ptype, Rules: rules})
+}
+
+func (w *CasbinWatcher) publish(msg policyMessage) error {
+	msg.Instance = w.instance
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.rdb.Publish(ctx, CasbinPolicyChannel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish policy change: %w", err)
+	}
+	return nil
+}
+
+// apply 把其他节点的变更应用到本节点内存
+// 变更已经由发送方写入数据库，这里必须关闭 AutoSave，否则会重复写 casbin_rule
+func (w *CasbinWatcher) apply(e *casbin.SyncedEnforcer, payload string) {
+	var msg policyMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		w.logger.Error("Casbin policy message malformed", "err", err)
+		return
+	}
+	if msg.Instance == w.instance {
+		return // 自己发出的变更，内存里已经是最新的
+	}
+
+	start := time.Now()
+	var err error
+	if msg.Op == policyOpReload {
+		err = e.LoadPolicy()
+	} else {
+		err = w.applyIncremental(e, msg)
+	}
+
+	attrs := []any{
+		slog.String("op", msg.Op),
+		slog.String("ptype", msg.Ptype),
+		slog.Int("rules", len(msg.Rules)),
+		slog.String("source", msg.Instance),
+		slog.Duration("latency", time.Since(start)),
+	}
+	if err != nil {
+		w.logger.Error("Casbin policy reload failed", append(attrs, slog.String("err", err.Error()))...)
+		return
+	}
+	w.logger.Info("Casbin policy reloaded", attrs...)
+}
+
+func (w *CasbinWatcher) applyIncremental(e *casbin.SyncedEnforcer, msg policyMessage) error {
+	// 与 SyncedEnforcer 的所有写操作共用一把锁，期间不会有本地写入看到 AutoSave=false
+	lock := e.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	e.Enforcer.EnableAutoSave(false)
+	defer e.Enforcer.EnableAutoSave(true)
+
+	var err error
+	switch msg.Op {
+	case policyOpAdd:
+		_, err = e.Enforcer.SelfAddPolicies(msg.Sec, msg.Ptype, msg.Rules)
+	case policyOpRemove:
+		_, err = e.Enforcer.SelfRemovePolicies(msg.Sec, msg.Ptype, msg.Rules)
+	case policyOpRemoveFiltered:
+		_, err = e.Enforcer.SelfRemoveFilteredPolicy(msg.Sec, msg.Ptype, msg.FieldIndex, msg.FieldValues...)
+	default:
+		err = fmt.Errorf("unknown policy op %q", msg.Op)
+	}
+	return err
+}
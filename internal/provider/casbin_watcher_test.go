@@ -0,0 +1,59 @@
+package provider_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-artisan/internal/provider"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newNode 模拟一个 Server 实例：独立的 Enforcer + 订阅同一个 Redis
+func newNode(t *testing.T, addr string) *casbin.SyncedEnforcer {
+	t.Helper()
+	e, err := casbin.NewSyncedEnforcer(provider.NewCasbinModel())
+	require.NoError(t, err)
+
+	w := provider.NewCasbinWatcher(redis.NewClient(&redis.Options{Addr: addr}), slog.Default())
+	require.NoError(t, w.Attach(e))
+	require.NoError(t, w.Start(context.Background()))
+	t.Cleanup(w.Close)
+	return e
+}
+
+func TestCasbinWatcher_SyncsPolicyAcrossNodes(t *testing.T) {
+	addr := miniredis.RunT(t).Addr()
+	nodeA := newNode(t, addr)
+	nodeB := newNode(t, addr)
+
+	allowed := func(e *casbin.SyncedEnforcer) func() bool {
+		return func() bool {
+			ok, _ := e.Enforce("user:1", "/api/orders/42", "GET")
+			return ok
+		}
+	}
+
+	// 节点 A 授权，节点 B 无需重启即可生效
+	_, err := nodeA.AddPolicy("role:sales", "/api/orders/:id", "GET")
+	require.NoError(t, err)
+	_, err = nodeA.AddGroupingPolicy("user:1", "role:sales")
+	require.NoError(t, err)
+	assert.Eventually(t, allowed(nodeB), time.Second, 10*time.Millisecond)
+
+	// 节点 B 收回角色，节点 A 同步失效
+	_, err = nodeB.RemoveGroupingPolicy("user:1", "role:sales")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return !allowed(nodeA)() }, time.Second, 10*time.Millisecond)
+
+	// 自己发出的消息不会被重复应用
+	policies, err := nodeA.GetPolicy()
+	require.NoError(t, err)
+	assert.Len(t, policies, 1)
+}
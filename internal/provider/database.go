@@ -12,6 +12,11 @@ var Module = fx.Options(
 	fx.Provide(NewDatabase),
 	fx.Provide(NewRedis),          // 👈 注册 Redis
 	fx.Provide(NewCasbinEnforcer), // 👈 注册 Casbin
+	fx.Provide(NewCasbinWatcher),  // 👈 多实例策略同步
+	fx.Invoke(RegisterCasbinWatcher),
+	fx.Provide(NewMailer),         // 👈 邮件 (log / file / smtp)
+	fx.Provide(NewHasher),         // 👈 密码哈希 (argon2id / bcrypt)
+	fx.Provide(NewPasswordPolicy), // 👈 密码策略
 )
 
 // NewDatabase 负责初始化 DB 并设置连接池参数
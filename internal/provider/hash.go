@@ -0,0 +1,11 @@
+package provider
+
+import (
+	"go-artisan/internal/config"
+	"go-artisan/pkg/hash"
+)
+
+// NewHasher 根据 hash.driver 创建密码哈希器
+func NewHasher(cfg *config.Config) (hash.Hasher, error) {
+	return hash.New(cfg.Hash.HasherConfig())
+}
@@ -0,0 +1,23 @@
+package provider
+
+import (
+	"fmt"
+	"log/slog"
+
+	"go-artisan/internal/config"
+	"go-artisan/pkg/mail"
+)
+
+// NewMailer 根据 mail.driver 选择实现：log (默认) / file / smtp
+func NewMailer(cfg *config.Config, logger *slog.Logger) (mail.Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "", "log":
+		return mail.NewLogMailer(cfg.Mail.From, logger), nil
+	case "file":
+		return mail.NewFileMailer(cfg.Mail.From, cfg.Mail.FileDir)
+	case "smtp":
+		return mail.NewSMTPMailer(cfg.Mail.From, cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, cfg.Mail.Password)
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q", cfg.Mail.Driver)
+	}
+}
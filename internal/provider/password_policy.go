@@ -0,0 +1,11 @@
+package provider
+
+import (
+	"go-artisan/internal/config"
+	"go-artisan/pkg/validator"
+)
+
+// NewPasswordPolicy 加载密码策略 (包括泄露密码列表)，列表文件缺失时拒绝启动
+func NewPasswordPolicy(cfg *config.Config) (*validator.PasswordPolicy, error) {
+	return validator.NewPasswordPolicy(cfg.Auth.PasswordPolicy.PolicyConfig())
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-artisan/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepo 实现
+type APIKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepo(db *gorm.DB) domain.APIKeyRepository {
+	return &APIKeyRepo{db: db}
+}
+
+var _ domain.APIKeyRepository = (*APIKeyRepo)(nil)
+
+func (r *APIKeyRepo) Create(ctx context.Context, key *domain.APIKey) error {
+	return translate(conn(ctx, r.db).Create(key).Error)
+}
+
+func (r *APIKeyRepo) FindByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := conn(ctx, r.db).Where("key_hash = ?", hash).First(&key).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepo) ListByUserID(ctx context.Context, userID uint) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	err := conn(ctx, r.db).Where("user_id = ?", userID).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *APIKeyRepo) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	res := conn(ctx, r.db).Where("id = ? AND user_id = ?", id, userID).Delete(&domain.APIKey{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *APIKeyRepo) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	return conn(ctx, r.db).Model(&domain.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (r *APIKeyRepo) DeleteByUserID(ctx context.Context, userID uint) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&domain.APIKey{}).Error
+}
@@ -0,0 +1,419 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go-artisan/pkg/errs"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// 过滤、排序、游标来自请求参数，非法时渲染为 422
+var (
+	ErrInvalidFilter = errs.Validation(42209, "invalid filter field")
+	ErrInvalidSort   = errs.Validation(42210, "invalid sort field")
+	ErrInvalidCursor = errs.Validation(42211, "invalid cursor")
+	// ErrNotSoftDeletable 模型没有 gorm.DeletedAt 字段，不支持 Restore；属于调用方的编程错误，按内部错误渲染为 500
+	ErrNotSoftDeletable = errors.New("model does not support soft delete")
+)
+
+// Op 过滤条件的比较运算符
+type Op string
+
+const (
+	OpEq      Op = "="
+	OpNe      Op = "<>"
+	OpGt      Op = ">"
+	OpGte     Op = ">="
+	OpLt      Op = "<"
+	OpLte     Op = "<="
+	OpLike    Op = "LIKE"
+	OpIn      Op = "IN"
+	OpNull    Op = "IS NULL"
+	OpNotNull Op = "IS NOT NULL"
+)
+
+// Filter 一个过滤条件，Field 为模型的列名 (或 Go 字段名)，不存在的列会被拒绝
+type Filter struct {
+	Field string
+	Op    Op
+	Value any
+}
+
+func Eq(field string, value any) Filter  { return Filter{Field: field, Op: OpEq, Value: value} }
+func Ne(field string, value any) Filter  { return Filter{Field: field, Op: OpNe, Value: value} }
+func Gt(field string, value any) Filter  { return Filter{Field: field, Op: OpGt, Value: value} }
+func Gte(field string, value any) Filter { return Filter{Field: field, Op: OpGte, Value: value} }
+func Lt(field string, value any) Filter  { return Filter{Field: field, Op: OpLt, Value: value} }
+func Lte(field string, value any) Filter { return Filter{Field: field, Op: OpLte, Value: value} }
+func Like(field, pattern string) Filter  { return Filter{Field: field, Op: OpLike, Value: pattern} }
+func IsNull(field string) Filter         { return Filter{Field: field, Op: OpNull} }
+func NotNull(field string) Filter        { return Filter{Field: field, Op: OpNotNull} }
+func In[V any](field string, values []V) Filter {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return Filter{Field: field, Op: OpIn, Value: args}
+}
+
+// ListOptions 列表查询参数
+//
+// Page > 0 时使用偏移分页 (返回 Total)；否则使用游标分页，Cursor 为上一页返回的 NextCursor，为空表示第一页
+type ListOptions struct {
+	Filters []Filter
+	Sort    string // 列名，前缀 "-" 表示倒序；为空时按主键倒序
+	Page    int
+	Cursor  string
+	PerPage int // 默认 20，最大 100
+}
+
+// Page 一页查询结果
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total,omitempty"` // 仅偏移分页
+	Page       int    `json:"page,omitempty"`  // 仅偏移分页
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"` // 仅游标分页，为空表示没有下一页
+}
+
+// cursor 游标分页的位置：上一页最后一行的排序列值与主键 (排序列相同时按主键决出先后)
+// Sort 记录生成游标时的排序 (如 -views)，换了排序再用旧游标会得到错乱的结果，直接拒绝
+type cursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v,omitempty"`
+	ID    json.RawMessage `json:"id"`
+}
+
+// cursorSort 排序列与方向的标识，倒序加 - 前缀
+func cursorSort(sortField *schema.Field, desc bool) string {
+	if desc {
+		return "-" + sortField.DBName
+	}
+	return sortField.DBName
+}
+
+// Base 通用 GORM 仓储，提供增删改查和分页列表
+//
+// 具体仓储嵌入 *Base[T] 并只补充业务查询，例如:
+//
+//	type UserRepo struct{ *Base[domain.User] }
+//
+// 排序只允许主键和构造时声明的列 (游标分页要求这些列 NOT NULL)，避免用户输入拼进 ORDER BY 或命中无索引的列
+type Base[T any] struct {
+	db       *gorm.DB
+	schema   *schema.Schema
+	sortable []string
+}
+
+// NewBase 解析模型结构，模型定义有误时 panic (属于编程错误，启动时即可发现)
+func NewBase[T any](db *gorm.DB, sortable ...string) *Base[T] {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		panic(fmt.Sprintf("repository: parse model %T: %v", *new(T), err))
+	}
+	if stmt.Schema.PrioritizedPrimaryField == nil {
+		panic(fmt.Sprintf("repository: model %T has no primary key", *new(T)))
+	}
+	return &Base[T]{db: db, schema: stmt.Schema, sortable: sortable}
+}
+
+// DB 绑定了请求上下文的连接 (context 中有事务时加入该事务)，供嵌入方编写自定义查询
+func (r *Base[T]) DB(ctx context.Context) *gorm.DB {
+	return conn(ctx, r.db)
+}
+
+func (r *Base[T]) Create(ctx context.Context, model *T) error {
+	return translate(r.DB(ctx).Create(model).Error)
+}
+
+func (r *Base[T]) Update(ctx context.Context, model *T) error {
+	return translate(r.DB(ctx).Save(model).Error)
+}
+
+// Delete 按主键删除，不存在时返回 false；模型带 gorm.DeletedAt 字段时为软删除
+func (r *Base[T]) Delete(ctx context.Context, id uint) (bool, error) {
+	return affected(r.DB(ctx).Delete(new(T), id))
+}
+
+// ForceDelete 按主键物理删除，已软删除的行同样会被删除
+func (r *Base[T]) ForceDelete(ctx context.Context, id uint) (bool, error) {
+	return affected(r.DB(ctx).Unscoped().Delete(new(T), id))
+}
+
+// Restore 恢复软删除的行，不存在或未被删除时返回 false
+func (r *Base[T]) Restore(ctx context.Context, id uint) (bool, error) {
+	field := r.deletedAtField()
+	if field == nil {
+		return false, ErrNotSoftDeletable
+	}
+	return affected(r.DB(ctx).Unscoped().Model(new(T)).
+		Where(clause.Eq{Column: clause.Column{Name: r.schema.PrioritizedPrimaryField.DBName}, Value: id}).
+		Where(clause.Neq{Column: clause.Column{Name: field.DBName}, Value: nil}).
+		Update(field.DBName, nil))
+}
+
+// deletedAtField 软删除字段 (类型为 gorm.DeletedAt)，没有时返回 nil
+func (r *Base[T]) deletedAtField() *schema.Field {
+	for _, field := range r.schema.Fields {
+		if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return field
+		}
+	}
+	return nil
+}
+
+func (r *Base[T]) FindByID(ctx context.Context, id uint) (*T, error) {
+	var model T
+	if err := r.DB(ctx).First(&model, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &model, nil
+}
+
+// FindOne 返回满足全部条件的第一行 (按主键)，没有时返回 errs.ErrNotFound
+func (r *Base[T]) FindOne(ctx context.Context, filters ...Filter) (*T, error) {
+	query, err := r.where(r.DB(ctx), filters)
+	if err != nil {
+		return nil, err
+	}
+	var model T
+	if err := query.First(&model).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &model, nil
+}
+
+// List 按条件分页查询
+func (r *Base[T]) List(ctx context.Context, opts ListOptions) (*Page[T], error) {
+	query, err := r.where(r.DB(ctx).Model(new(T)), opts.Filters)
+	if err != nil {
+		return nil, err
+	}
+	sortField, desc, err := r.sortField(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	perPage := opts.PerPage
+	if perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	perPage = min(perPage, maxPerPage)
+
+	if opts.Page > 0 {
+		return r.offsetPage(query, sortField, desc, opts.Page, perPage)
+	}
+	return r.cursorPage(ctx, query, sortField, desc, opts.Cursor, perPage)
+}
+
+func (r *Base[T]) offsetPage(query *gorm.DB, sortField *schema.Field, desc bool, page, perPage int) (*Page[T], error) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, translate(err)
+	}
+
+	items := make([]T, 0, perPage)
+	err := r.order(query, sortField, desc).
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&items).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &Page[T]{Items: items, Total: total, Page: page, PerPage: perPage}, nil
+}
+
+// cursorPage 键集分页：WHERE (sort, id) 在游标之后，不受前面翻页时新增/删除行的影响，也不需要 COUNT
+func (r *Base[T]) cursorPage(ctx context.Context, query *gorm.DB, sortField *schema.Field, desc bool, token string, perPage int) (*Page[T], error) {
+	pk := r.schema.PrioritizedPrimaryField
+	if token != "" {
+		after, err := r.decodeCursor(token, sortField, desc)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(after)
+	}
+
+	// 多取一行用来判断是否还有下一页
+	items := make([]T, 0, perPage+1)
+	if err := r.order(query, sortField, desc).Limit(perPage + 1).Find(&items).Error; err != nil {
+		return nil, translate(err)
+	}
+
+	page := &Page[T]{Items: items, PerPage: perPage}
+	if len(items) > perPage {
+		page.Items = items[:perPage]
+		last := reflect.ValueOf(&page.Items[perPage-1]).Elem()
+
+		c := cursor{Sort: cursorSort(sortField, desc)}
+		var err error
+		if c.ID, err = fieldJSON(ctx, pk, last); err != nil {
+			return nil, err
+		}
+		if sortField != pk {
+			if c.Value, err = fieldJSON(ctx, sortField, last); err != nil {
+				return nil, err
+			}
+		}
+		data, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(data)
+	}
+	return page, nil
+}
+
+// decodeCursor 把游标还原成 "排在它之后" 的条件；值按字段的 Go 类型解码 (如 time.Time)，交给驱动正确绑定
+func (r *Base[T]) decodeCursor(token string, sortField *schema.Field, desc bool) (clause.Expression, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != cursorSort(sortField, desc) {
+		return nil, ErrInvalidCursor
+	}
+
+	after := func(field *schema.Field, value any) clause.Expression {
+		col := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+		if desc {
+			return clause.Lt{Column: col, Value: value}
+		}
+		return clause.Gt{Column: col, Value: value}
+	}
+
+	pk := r.schema.PrioritizedPrimaryField
+	id, err := decodeField(pk, c.ID)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if sortField == pk {
+		return after(pk, id), nil
+	}
+
+	value, err := decodeField(sortField, c.Value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	// (sort, id) > (v, id0)  =>  sort > v OR (sort = v AND id > id0)
+	return clause.Or(
+		after(sortField, value),
+		clause.And(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: sortField.DBName}, Value: value}, after(pk, id)),
+	), nil
+}
+
+// where 校验并拼接过滤条件
+func (r *Base[T]) where(query *gorm.DB, filters []Filter) (*gorm.DB, error) {
+	for _, f := range filters {
+		expr, err := r.condition(f)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(expr)
+	}
+	return query, nil
+}
+
+func (r *Base[T]) condition(f Filter) (clause.Expression, error) {
+	field := r.schema.LookUpField(f.Field)
+	if field == nil || field.DBName == "" {
+		return nil, ErrInvalidFilter.WithMessage(fmt.Sprintf("invalid filter field: %s", f.Field))
+	}
+	col := clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+
+	switch f.Op {
+	case OpEq:
+		return clause.Eq{Column: col, Value: f.Value}, nil
+	case OpNe:
+		return clause.Neq{Column: col, Value: f.Value}, nil
+	case OpGt:
+		return clause.Gt{Column: col, Value: f.Value}, nil
+	case OpGte:
+		return clause.Gte{Column: col, Value: f.Value}, nil
+	case OpLt:
+		return clause.Lt{Column: col, Value: f.Value}, nil
+	case OpLte:
+		return clause.Lte{Column: col, Value: f.Value}, nil
+	case OpLike:
+		return clause.Like{Column: col, Value: f.Value}, nil
+	case OpIn:
+		values, _ := f.Value.([]any)
+		return clause.IN{Column: col, Values: values}, nil
+	case OpNull:
+		return clause.Eq{Column: col, Value: nil}, nil
+	case OpNotNull:
+		return clause.Neq{Column: col, Value: nil}, nil
+	default:
+		return nil, ErrInvalidFilter.WithMessage(fmt.Sprintf("invalid filter field: unsupported operator %q", f.Op))
+	}
+}
+
+// sortField 解析 "-created_at" 形式的排序参数，只接受主键和白名单中的列
+func (r *Base[T]) sortField(sort string) (*schema.Field, bool, error) {
+	pk := r.schema.PrioritizedPrimaryField
+	if sort == "" {
+		return pk, true, nil
+	}
+
+	name, desc := strings.CutPrefix(sort, "-")
+	if name == pk.DBName {
+		return pk, desc, nil
+	}
+	if !slices.Contains(r.sortable, name) {
+		return nil, false, ErrInvalidSort.WithMessage(fmt.Sprintf("invalid sort field: %s", name))
+	}
+	field := r.schema.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		return nil, false, ErrInvalidSort.WithMessage(fmt.Sprintf("invalid sort field: %s", name))
+	}
+	return field, desc, nil
+}
+
+// order 先按排序列，再按主键同方向排序，保证顺序稳定 (游标分页依赖这一点)
+func (r *Base[T]) order(query *gorm.DB, sortField *schema.Field, desc bool) *gorm.DB {
+	pk := r.schema.PrioritizedPrimaryField
+	if sortField != pk {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: sortField.DBName}, Desc: desc})
+	}
+	return query.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Desc: desc})
+}
+
+// affected 写操作的结果，未影响任何行时返回 false
+func affected(res *gorm.DB) (bool, error) {
+	if res.Error != nil {
+		return false, translate(res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func fieldJSON(ctx context.Context, field *schema.Field, row reflect.Value) (json.RawMessage, error) {
+	value, _ := field.ValueOf(ctx, row)
+	return json.Marshal(value)
+}
+
+func decodeField(field *schema.Field, raw json.RawMessage) (any, error) {
+	if len(raw) == 0 {
+		return nil, ErrInvalidCursor
+	}
+	ptr := reflect.New(field.FieldType)
+	if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
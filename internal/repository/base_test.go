@@ -0,0 +1,193 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-artisan/internal/repository"
+	"go-artisan/pkg/errs"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type article struct {
+	ID        uint
+	Title     string
+	Views     int
+	CreatedAt time.Time
+	DeletedBy *uint
+}
+
+// openTestDB 内存 SQLite；只用一个连接，否则每个新连接都是一个空库
+func openTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	require.NoError(t, db.AutoMigrate(&article{}))
+	return db
+}
+
+func newTestBase(t *testing.T) (*repository.Base[article], context.Context) {
+	db := openTestDB(t)
+	repo := repository.NewBase[article](db, "views", "created_at")
+	ctx := context.Background()
+
+	// 10 篇文章，views 只有 3 种取值，验证排序列相同时按主键决出先后
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 10; i++ {
+		require.NoError(t, repo.Create(ctx, &article{
+			Title:     fmt.Sprintf("post-%d", i),
+			Views:     i % 3,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+	return repo, ctx
+}
+
+func titles(items []article) []string {
+	out := make([]string, len(items))
+	for i, a := range items {
+		out[i] = a.Title
+	}
+	return out
+}
+
+func TestBase_CRUD(t *testing.T) {
+	repo, ctx := newTestBase(t)
+
+	a, err := repo.FindOne(ctx, repository.Eq("title", "post-3"))
+	require.NoError(t, err)
+
+	a.Views = 99
+	require.NoError(t, repo.Update(ctx, a))
+	got, err := repo.FindByID(ctx, a.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 99, got.Views)
+
+	ok, err := repo.Delete(ctx, a.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.Delete(ctx, a.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = repo.FindByID(ctx, a.ID)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	_, err = repo.FindOne(ctx, repository.Eq("title; DROP TABLE articles", "x"))
+	assert.ErrorIs(t, err, repository.ErrInvalidFilter)
+	assert.ErrorIs(t, err, errs.ErrValidation, "非法参数渲染为 422 而不是 500")
+}
+
+func TestBase_List_Offset(t *testing.T) {
+	repo, ctx := newTestBase(t)
+
+	page, err := repo.List(ctx, repository.ListOptions{
+		Filters: []repository.Filter{repository.In("views", []int{1, 2}), repository.IsNull("deleted_by")},
+		Sort:    "created_at",
+		Page:    2,
+		PerPage: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), page.Total)
+	assert.Equal(t, []string{"post-5", "post-7", "post-8"}, titles(page.Items))
+
+	_, err = repo.List(ctx, repository.ListOptions{Sort: "title"})
+	assert.ErrorIs(t, err, repository.ErrInvalidSort)
+	assert.ErrorIs(t, err, errs.ErrValidation)
+}
+
+func TestBase_List_Cursor(t *testing.T) {
+	repo, ctx := newTestBase(t)
+
+	// 按 views 倒序翻完全部数据，不重不漏
+	var all []string
+	opts := repository.ListOptions{Sort: "-views", PerPage: 4}
+	for range 5 {
+		page, err := repo.List(ctx, opts)
+		require.NoError(t, err)
+		all = append(all, titles(page.Items)...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{
+		"post-8", "post-5", "post-2", // views = 2
+		"post-10", "post-7", "post-4", "post-1", // views = 1
+		"post-9", "post-6", "post-3", // views = 0
+	}, all)
+
+	// 时间列游标按字段类型解码
+	page, err := repo.List(ctx, repository.ListOptions{Sort: "created_at", PerPage: 6})
+	require.NoError(t, err)
+	page, err = repo.List(ctx, repository.ListOptions{Sort: "created_at", PerPage: 6, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"post-7", "post-8", "post-9", "post-10"}, titles(page.Items))
+	assert.Empty(t, page.NextCursor)
+
+	_, err = repo.List(ctx, repository.ListOptions{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+
+	// 游标只能用于生成它时的排序列与方向
+	page, err = repo.List(ctx, repository.ListOptions{Sort: "-views", PerPage: 4})
+	require.NoError(t, err)
+	for _, sort := range []string{"views", "-created_at", ""} {
+		_, err = repo.List(ctx, repository.ListOptions{Sort: sort, PerPage: 4, Cursor: page.NextCursor})
+		assert.ErrorIs(t, err, repository.ErrInvalidCursor, sort)
+	}
+}
+
+type note struct {
+	ID        uint
+	Body      string
+	DeletedAt gorm.DeletedAt
+}
+
+func TestBase_SoftDelete(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.AutoMigrate(&note{}))
+	repo := repository.NewBase[note](db)
+	ctx := context.Background()
+
+	n := &note{Body: "hello"}
+	require.NoError(t, repo.Create(ctx, n))
+
+	ok, err := repo.Delete(ctx, n.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, err = repo.FindByID(ctx, n.ID)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+
+	ok, err = repo.Restore(ctx, n.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.Restore(ctx, n.ID)
+	require.NoError(t, err)
+	assert.False(t, ok, "未删除的行不需要恢复")
+	_, err = repo.FindByID(ctx, n.ID)
+	require.NoError(t, err)
+
+	// 物理删除对已软删除的行同样生效
+	_, err = repo.Delete(ctx, n.ID)
+	require.NoError(t, err)
+	ok, err = repo.ForceDelete(ctx, n.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&note{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	_, err = repository.NewBase[article](db).Restore(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrNotSoftDeletable)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+
+	"go-artisan/pkg/errs"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	mysqlErrDuplicateEntry  = 1062 // 违反唯一索引
+	mysqlErrRowIsReferenced = 1451 // 删除的行仍被其他表的外键引用
+)
+
+// translate 把 GORM / MySQL 错误转换为 errs 中的通用错误，原始错误保留在错误链中便于排查
+// 上层只需判断 errs.ErrNotFound / errs.ErrConflict，不依赖具体的 ORM 和数据库
+func translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errs.ErrNotFound.Wrap(err)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.Is(err, gorm.ErrDuplicatedKey) || (errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry) {
+		return errs.ErrConflict.Wrap(err)
+	}
+	if errors.Is(err, gorm.ErrForeignKeyViolated) || (errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrRowIsReferenced) {
+		return errs.ErrConflict.Wrap(err)
+	}
+	return err
+}
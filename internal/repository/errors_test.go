@@ -0,0 +1,45 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-artisan/internal/repository"
+	"go-artisan/pkg/errs"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// 测试用 SQLite，MySQL 的错误通过回调注入，验证仓储返回的错误与数据库无关
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbErr    error
+		conflict bool
+	}{
+		{"唯一索引冲突", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'a' for key 'title'"}, true},
+		{"外键仍被引用", &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"}, true},
+		{"GORM 统一的重复键错误", gorm.ErrDuplicatedKey, true},
+		{"其他错误原样返回", &mysql.MySQLError{Number: 1213, Message: "Deadlock found"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+				_ = tx.AddError(tt.dbErr)
+			}))
+
+			err := repository.NewBase[article](db).Create(context.Background(), &article{Title: "a"})
+			require.Error(t, err)
+			assert.Equal(t, tt.conflict, errors.Is(err, errs.ErrConflict))
+			assert.ErrorIs(t, err, tt.dbErr, "原始错误保留在错误链中")
+		})
+	}
+
+	_, err := repository.NewBase[article](openTestDB(t)).FindByID(context.Background(), 404)
+	assert.ErrorIs(t, err, errs.ErrNotFound)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"go-artisan/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// OAuthClientRepo 实现
+type OAuthClientRepo struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepo(db *gorm.DB) domain.OAuthClientRepository {
+	return &OAuthClientRepo{db: db}
+}
+
+var _ domain.OAuthClientRepository = (*OAuthClientRepo)(nil)
+
+func (r *OAuthClientRepo) Create(ctx context.Context, client *domain.OAuthClient) error {
+	return translate(conn(ctx, r.db).Create(client).Error)
+}
+
+func (r *OAuthClientRepo) FindByClientID(ctx context.Context, clientID string) (*domain.OAuthClient, error) {
+	var client domain.OAuthClient
+	err := conn(ctx, r.db).Where("client_id = ?", clientID).First(&client).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &client, nil
+}
+
+func (r *OAuthClientRepo) List(ctx context.Context) ([]domain.OAuthClient, error) {
+	var clients []domain.OAuthClient
+	err := conn(ctx, r.db).Order("id").Find(&clients).Error
+	return clients, err
+}
+
+func (r *OAuthClientRepo) Delete(ctx context.Context, clientID string) (bool, error) {
+	res := conn(ctx, r.db).Where("client_id = ?", clientID).Delete(&domain.OAuthClient{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
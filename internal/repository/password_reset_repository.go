@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-artisan/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// PasswordResetRepo 实现
+type PasswordResetRepo struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepo(db *gorm.DB) domain.PasswordResetRepository {
+	return &PasswordResetRepo{db: db}
+}
+
+var _ domain.PasswordResetRepository = (*PasswordResetRepo)(nil)
+
+func (r *PasswordResetRepo) Create(ctx context.Context, token *domain.PasswordResetToken) error {
+	return translate(conn(ctx, r.db).Create(token).Error)
+}
+
+func (r *PasswordResetRepo) FindByHash(ctx context.Context, hash string) (*domain.PasswordResetToken, error) {
+	var token domain.PasswordResetToken
+	err := conn(ctx, r.db).Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *PasswordResetRepo) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	res := conn(ctx, r.db).Model(&domain.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *PasswordResetRepo) DeleteByUserID(ctx context.Context, userID uint) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&domain.PasswordResetToken{}).Error
+}
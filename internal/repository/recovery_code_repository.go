@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-artisan/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepo 实现
+type RecoveryCodeRepo struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepo(db *gorm.DB) domain.RecoveryCodeRepository {
+	return &RecoveryCodeRepo{db: db}
+}
+
+var _ domain.RecoveryCodeRepository = (*RecoveryCodeRepo)(nil)
+
+func (r *RecoveryCodeRepo) ReplaceAll(ctx context.Context, userID uint, hashes []string) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]domain.RecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			codes = append(codes, domain.RecoveryCode{UserID: userID, CodeHash: h})
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *RecoveryCodeRepo) Consume(ctx context.Context, userID uint, hash string) (bool, error) {
+	res := conn(ctx, r.db).Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Limit(1).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *RecoveryCodeRepo) DeleteByUserID(ctx context.Context, userID uint) error {
+	return conn(ctx, r.db).Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error
+}
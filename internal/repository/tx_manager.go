@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"go-artisan/internal/domain"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	// txMaxAttempts 死锁时最多执行事务的次数 (含第一次)
+	txMaxAttempts = 3
+	// txRetryBackoff 重试前的基础等待时间，每次翻倍并加随机抖动，避免冲突双方同时重试再次死锁
+	txRetryBackoff = 20 * time.Millisecond
+)
+
+// MySQL 锁冲突错误，整个事务回滚后重试通常就能成功
+const (
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrDeadlock        = 1213
+)
+
+// txKey context 中保存当前事务的 key
+type txKey struct{}
+
+// TxManager 基于 GORM 的事务管理器，事务放在 context 中向下传递
+type TxManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) domain.TxManager {
+	return &TxManager{db: db}
+}
+
+var _ domain.TxManager = (*TxManager)(nil)
+
+func (m *TxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	// 已在事务中：GORM 对事务连接再开 Transaction 会使用 SAVEPOINT
+	// 死锁时 MySQL 回滚的是整个事务，所以只由最外层重试
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx).Transaction(func(inner *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, inner))
+		})
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		})
+		if err == nil || !retryable(err) || attempt == txMaxAttempts {
+			return err
+		}
+
+		backoff := txRetryBackoff << (attempt - 1)
+		backoff += rand.N(backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// conn 返回 context 中的事务 (如果有)，否则返回普通连接；仓储都应通过它执行 SQL
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+func retryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == mysqlErrDeadlock || mysqlErr.Number == mysqlErrLockWaitTimeout
+}
@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-artisan/internal/domain"
+	"go-artisan/internal/repository"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_WithinTransaction(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.NewBase[article](db)
+	txm := repository.NewTxManager(db)
+	ctx := context.Background()
+	errBoom := errors.New("boom")
+
+	count := func() int {
+		page, err := repo.List(ctx, repository.ListOptions{Page: 1})
+		require.NoError(t, err)
+		return int(page.Total)
+	}
+
+	t.Run("fn 返回错误时回滚全部写入", func(t *testing.T) {
+		err := txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, repo.Create(ctx, &article{Title: "a"}))
+			require.NoError(t, repo.Create(ctx, &article{Title: "b"}))
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+		assert.Equal(t, 0, count())
+	})
+
+	t.Run("嵌套事务失败只回滚到 SAVEPOINT", func(t *testing.T) {
+		err := txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, repo.Create(ctx, &article{Title: "outer"}))
+			inner := txm.WithinTransaction(ctx, func(ctx context.Context) error {
+				require.NoError(t, repo.Create(ctx, &article{Title: "inner"}))
+				return errBoom
+			})
+			assert.ErrorIs(t, inner, errBoom)
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = repo.FindOne(ctx, repository.Eq("title", "outer"))
+		assert.NoError(t, err)
+		_, err = repo.FindOne(ctx, repository.Eq("title", "inner"))
+		assert.Error(t, err)
+	})
+
+	t.Run("死锁时重试整个事务", func(t *testing.T) {
+		before, attempts := count(), 0
+		err := txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			require.NoError(t, repo.Create(ctx, &article{Title: "retried"}))
+			if attempts < 3 {
+				return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, before+1, count())
+
+		attempts = 0
+		err = txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			attempts++
+			return &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts, "其他错误不重试")
+	})
+
+	t.Run("专用仓储的写入同样加入事务", func(t *testing.T) {
+		require.NoError(t, db.AutoMigrate(&domain.APIKey{}))
+		keys := repository.NewAPIKeyRepo(db)
+
+		err := txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			require.NoError(t, keys.Create(ctx, &domain.APIKey{UserID: 1, Name: "ci", Prefix: "ak_", KeyHash: "hash"}))
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+
+		list, err := keys.ListByUserID(ctx, 1)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"go-artisan/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentityRepo 实现
+type UserIdentityRepo struct {
+	*Base[domain.UserIdentity]
+}
+
+func NewUserIdentityRepo(db *gorm.DB) domain.UserIdentityRepository {
+	return &UserIdentityRepo{Base: NewBase[domain.UserIdentity](db)}
+}
+
+var _ domain.UserIdentityRepository = (*UserIdentityRepo)(nil)
+
+func (r *UserIdentityRepo) FindBySubject(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	return r.FindOne(ctx, Eq("provider", provider), Eq("subject", subject))
+}
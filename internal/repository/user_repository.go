@@ -1,39 +1,63 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"go-artisan/internal/domain"
 
 	"gorm.io/gorm"
 )
 
-// UserRepo 实现
+// UserRepo 实现，通用的增删改查由 Base 提供
 type UserRepo struct {
-	db *gorm.DB
+	*Base[domain.User]
 }
 
 // NewUserRepo 构造函数，自动注入 gorm.DB
 func NewUserRepo(db *gorm.DB) domain.UserRepository {
-	return &UserRepo{db: db}
+	return &UserRepo{Base: NewBase[domain.User](db, "name", "email", "created_at")}
 }
 
 // 确保实现了接口
 var _ domain.UserRepository = (*UserRepo)(nil)
 
-func (r *UserRepo) Create(user *domain.User) error {
-	return r.db.Create(user).Error
+func (r *UserRepo) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.FindOne(ctx, Eq("email", email))
 }
 
-func (r *UserRepo) FindByEmail(email string) (*domain.User, error) {
-	var user domain.User
-	err := r.db.Where("email = ?", email).First(&user).Error
-	return &user, err
+func (r *UserRepo) FindDeletedBefore(ctx context.Context, before time.Time, afterID uint, limit int) ([]domain.User, error) {
+	var users []domain.User
+	err := r.DB(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymized_at IS NULL AND id > ?", before, afterID).
+		Order("id").Limit(limit).Find(&users).Error
+	return users, translate(err)
 }
 
-func (r *UserRepo) FindByID(id uint) (*domain.User, error) {
-	var user domain.User
-	err := r.db.First(&user, id).Error
-	if err != nil {
-		return nil, err
+// Anonymize 邮箱改写为 deleted-<id>@anonymized.invalid (仍满足唯一索引)，密码置空后无法再登录
+// API Key、外部身份、重置 Token、恢复码没有保留价值，直接删除
+func (r *UserRepo) Anonymize(ctx context.Context, id uint) error {
+	db := r.DB(ctx)
+	for _, model := range []any{&domain.APIKey{}, &domain.UserIdentity{}, &domain.PasswordResetToken{}, &domain.RecoveryCode{}} {
+		if err := db.Where("user_id = ?", id).Delete(model).Error; err != nil {
+			return translate(err)
+		}
 	}
-	return &user, nil
+	return translate(db.Unscoped().Model(&domain.User{}).Where("id = ?", id).Updates(map[string]any{
+		"name":                    "Deleted User",
+		"email":                   fmt.Sprintf("deleted-%d@anonymized.invalid", id),
+		"password":                "",
+		"email_verified_at":       nil,
+		"two_factor_secret":       "",
+		"two_factor_confirmed_at": nil,
+		"anonymized_at":           time.Now(),
+	}).Error)
+}
+
+// Restore 匿名化之后个人信息已经抹去，恢复出来的只是一个空壳账户，因此不允许恢复
+func (r *UserRepo) Restore(ctx context.Context, id uint) (bool, error) {
+	return affected(r.DB(ctx).Unscoped().Model(&domain.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND anonymized_at IS NULL", id).
+		Update("deleted_at", nil))
 }
@@ -0,0 +1,43 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-artisan/internal/domain"
+	"go-artisan/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepo_AnonymizedUsers(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, db.AutoMigrate(&domain.User{}, &domain.APIKey{}, &domain.UserIdentity{}, &domain.PasswordResetToken{}, &domain.RecoveryCode{}))
+	repo := repository.NewUserRepo(db)
+	ctx := context.Background()
+
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		u := &domain.User{Name: "u", Email: email, Password: "x"}
+		require.NoError(t, repo.Create(ctx, u))
+		_, err := repo.Delete(ctx, u.ID)
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.Anonymize(ctx, 1))
+
+	// 已匿名化的用户不会被重复清除
+	users, err := repo.FindDeletedBefore(ctx, time.Now().Add(time.Minute), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, uint(2), users[0].ID)
+
+	// 也不能被恢复
+	ok, err := repo.Restore(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = repo.Restore(ctx, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-artisan/internal/domain"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/hash"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// purgeBatchSize 清除时每批读取的用户数
+	purgeBatchSize = 100
+	// reauthWindow 在这段时间内登录的会话注销账户时无需再次认证
+	reauthWindow = 5 * time.Minute
+)
+
+// ErrReauthRequired 注销账户既没有提供密码或验证码，会话也不是刚刚登录的
+var ErrReauthRequired = errs.Forbidden(40305, "please confirm with your password, a two-factor code or sign in again")
+
+// AccountService 账户注销、恢复与到期清除
+//
+// 注销只做软删除，保留期内管理员可以恢复；保留期过后由 artisan users:purge 调用 Purge，
+// 物理删除用户 (关联的认证数据由外键级联删除)，其他业务表仍引用该用户时改为匿名化
+type AccountService struct {
+	users       domain.UserRepository
+	apiKeys     domain.APIKeyRepository
+	tokens      *TokenService
+	twoFactor   *TwoFactorService
+	throttle    *LoginThrottle
+	permissions *PermissionService
+	hasher      hash.Hasher
+	txm         domain.TxManager
+	redis       *redis.Client
+}
+
+func NewAccountService(
+	users domain.UserRepository,
+	apiKeys domain.APIKeyRepository,
+	tokens *TokenService,
+	twoFactor *TwoFactorService,
+	throttle *LoginThrottle,
+	permissions *PermissionService,
+	hasher hash.Hasher,
+	txm domain.TxManager,
+	rdb *redis.Client,
+) *AccountService {
+	return &AccountService{
+		users:       users,
+		apiKeys:     apiKeys,
+		tokens:      tokens,
+		twoFactor:   twoFactor,
+		throttle:    throttle,
+		permissions: permissions,
+		hasher:      hasher,
+		txm:         txm,
+		redis:       rdb,
+	}
+}
+
+// DeactivateDTO 注销账户前的再次认证，满足其一即可
+// OIDC 自动创建的用户没有可用的密码，可以用两步验证码，或者重新登录后在 reauthWindow 内注销
+type DeactivateDTO struct {
+	CurrentPassword string
+	Code            string    // TOTP 验证码或恢复码 (需已开启两步验证)
+	AuthTime        time.Time // 当前会话的登录时间，未知时为零值
+}
+
+// Deactivate 用户注销自己的账户
+// 所有会话和 API Key 立即失效；API Key 直接删除，恢复账户后需要重新创建
+func (s *AccountService) Deactivate(ctx context.Context, id uint, req DeactivateDTO) error {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return ErrUserNotFound.Wrap(err)
+		}
+		return err
+	}
+	if err := s.reauthenticate(ctx, user, req); err != nil {
+		return err
+	}
+
+	// 先吊销 Token：即使后面删除失败，也不会留下账户已注销但会话仍然有效的状态
+	if err := s.tokens.RevokeAll(ctx, id); err != nil {
+		return err
+	}
+	// API Key 认证不查用户表，与软删除放在同一个事务里，不会出现只删了一半的情况
+	err = s.txm.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.apiKeys.DeleteByUserID(ctx, id); err != nil {
+			return err
+		}
+		_, err := s.users.Delete(ctx, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.redis.Del(ctx, profileCacheKey(id))
+	return nil
+}
+
+// reauthenticate 按密码、两步验证码、最近登录的顺序确认是本人操作，输错的密码和验证码按用户计数
+func (s *AccountService) reauthenticate(ctx context.Context, user *domain.User, req DeactivateDTO) error {
+	switch {
+	case req.CurrentPassword != "":
+		return checkCurrentPassword(ctx, s.throttle, s.hasher, user, req.CurrentPassword)
+	case req.Code != "":
+		return throttledReauth(ctx, s.throttle, user.ID, ErrInvalidTwoFactorCode, func() (bool, error) {
+			return s.twoFactor.Verify(ctx, user, req.Code)
+		})
+	case !req.AuthTime.IsZero() && time.Since(req.AuthTime) < reauthWindow:
+		return nil
+	}
+	return ErrReauthRequired
+}
+
+// Restore 管理员恢复保留期内注销的账户，用户需要重新登录；已经匿名化的账户无法恢复
+func (s *AccountService) Restore(ctx context.Context, id uint) error {
+	ok, err := s.users.Restore(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	s.redis.Del(ctx, profileCacheKey(id))
+	return nil
+}
+
+// PurgeResult 一次清除的统计
+type PurgeResult struct {
+	Deleted    int // 物理删除的用户数
+	Anonymized int // 仍被其他表引用、只做了匿名化的用户数
+}
+
+// Purge 清除 before 之前注销的全部用户：抹去个人信息、物理删除，并清理缓存和 Casbin 规则
+// 单个用户在一个事务中处理，中途失败时已处理的用户不会回滚，重新执行即可继续
+func (s *AccountService) Purge(ctx context.Context, before time.Time) (*PurgeResult, error) {
+	result := &PurgeResult{}
+	var afterID uint
+	for {
+		users, err := s.users.FindDeletedBefore(ctx, before, afterID, purgeBatchSize)
+		if err != nil {
+			return result, err
+		}
+		for _, user := range users {
+			anonymized, err := s.purge(ctx, &user)
+			if err != nil {
+				return result, err
+			}
+			if anonymized {
+				result.Anonymized++
+			} else {
+				result.Deleted++
+			}
+			afterID = user.ID
+		}
+		if len(users) < purgeBatchSize {
+			return result, nil
+		}
+	}
+}
+
+// purge 清除单个用户，返回是否只做了匿名化
+func (s *AccountService) purge(ctx context.Context, user *domain.User) (bool, error) {
+	id := user.ID
+	anonymized := false
+	err := s.txm.WithinTransaction(ctx, func(ctx context.Context) error {
+		// 先匿名化：即使物理删除失败，个人信息也已抹去
+		if err := s.users.Anonymize(ctx, id); err != nil {
+			return err
+		}
+		// 其他业务表仍引用该用户时删除会失败，只回滚到 SAVEPOINT，保留匿名化后的记录
+		err := s.txm.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := s.users.ForceDelete(ctx, id)
+			return err
+		})
+		if errors.Is(err, errs.ErrConflict) {
+			anonymized = true
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// Redis 和 Casbin 不在事务内，放在提交之后；user 是匿名化之前读出的，仍是原邮箱
+	s.redis.Del(ctx, profileCacheKey(id), emailResendKey(id))
+	if err := s.throttle.Forget(ctx, id, user.Email); err != nil {
+		return false, err
+	}
+	if err := s.tokens.ForgetUser(ctx, id); err != nil {
+		return false, err
+	}
+	// 最后一个管理员被清除时保留其角色分配：账户已匿名化无法登录，先给新管理员授权后再收回
+	if _, err := s.permissions.RemoveSubject(UserSubject(id)); err != nil && !errors.Is(err, ErrLastAdmin) {
+		return false, err
+	}
+	return anonymized, nil
+}
@@ -0,0 +1,195 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/domain/mocks"
+	"go-artisan/internal/provider"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/errs"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/casbin/casbin/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type accountFixture struct {
+	svc         *service.AccountService
+	users       *mocks.MockUserRepository
+	apiKeys     *mocks.MockAPIKeyRepository
+	codes       *mocks.MockRecoveryCodeRepository
+	tokens      *service.TokenService
+	permissions *service.PermissionService
+	mr          *miniredis.Miniredis
+}
+
+func newTestAccountService(t *testing.T) *accountFixture {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	apiKeys := mocks.NewMockAPIKeyRepository(ctrl)
+	codes := mocks.NewMockRecoveryCodeRepository(ctrl)
+	txm := mocks.NewMockTxManager(ctrl)
+	txm.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	}).AnyTimes()
+
+	cfg := &config.Config{
+		App: config.AppConfig{Key: "test-app-key"},
+		Auth: config.AuthConfig{
+			Secret:     "test-secret",
+			TTL:        15 * time.Minute,
+			RefreshTTL: 24 * time.Hour,
+			Issuer:     "go-artisan",
+			LoginThrottle: config.LoginThrottleConfig{
+				MaxAccountAttempts: 3,
+				MaxIPAttempts:      10,
+				Window:             time.Minute,
+				LockoutDuration:    time.Minute,
+			},
+		},
+	}
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	tokens, err := service.NewTokenService(cfg, rdb)
+	require.NoError(t, err)
+	twoFactor, err := service.NewTwoFactorService(users, codes, service.NewLoginThrottle(cfg, rdb), cfg, rdb)
+	require.NoError(t, err)
+
+	e, err := casbin.NewSyncedEnforcer(provider.NewCasbinModel())
+	require.NoError(t, err)
+	permissions := service.NewPermissionService(e)
+
+	return &accountFixture{
+		svc:         service.NewAccountService(users, apiKeys, tokens, twoFactor, service.NewLoginThrottle(cfg, rdb), permissions, newHasher(t), txm, rdb),
+		users:       users,
+		apiKeys:     apiKeys,
+		codes:       codes,
+		tokens:      tokens,
+		permissions: permissions,
+		mr:          mr,
+	}
+}
+
+func TestAccountService_Deactivate(t *testing.T) {
+	f := newTestAccountService(t)
+	ctx := context.Background()
+	hashed, err := newHasher(t).Hash("password123")
+	require.NoError(t, err)
+	f.users.EXPECT().FindByID(gomock.Any(), uint(1)).Return(&domain.User{ID: 1, Password: hashed}, nil).AnyTimes()
+
+	err = f.svc.Deactivate(ctx, 1, service.DeactivateDTO{CurrentPassword: "wrong-password"})
+	assert.ErrorIs(t, err, service.ErrCurrentPasswordMismatch)
+
+	// 没有密码也没有验证码时，只接受刚刚登录的会话
+	err = f.svc.Deactivate(ctx, 1, service.DeactivateDTO{AuthTime: time.Now().Add(-time.Hour)})
+	assert.ErrorIs(t, err, service.ErrReauthRequired)
+	err = f.svc.Deactivate(ctx, 1, service.DeactivateDTO{})
+	assert.ErrorIs(t, err, service.ErrReauthRequired)
+	err = f.svc.Deactivate(ctx, 1, service.DeactivateDTO{Code: "123456"})
+	assert.ErrorIs(t, err, service.ErrTwoFactorNotEnabled)
+
+	pair, err := f.tokens.Issue(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, f.mr.Set("user:profile:1", "{}"))
+
+	f.apiKeys.EXPECT().DeleteByUserID(gomock.Any(), uint(1)).Return(nil)
+	f.users.EXPECT().Delete(gomock.Any(), uint(1)).DoAndReturn(func(ctx context.Context, _ uint) (bool, error) {
+		_, err := f.tokens.Authenticate(ctx, pair.AccessToken)
+		assert.Error(t, err, "删除账户之前 Token 就应已吊销")
+		return true, nil
+	})
+	claims, err := f.tokens.Authenticate(ctx, pair.AccessToken)
+	require.NoError(t, err)
+	require.NotNil(t, claims.AuthTime)
+	require.NoError(t, f.svc.Deactivate(ctx, 1, service.DeactivateDTO{AuthTime: claims.AuthTime.Time}))
+
+	_, err = f.tokens.Authenticate(ctx, pair.AccessToken)
+	assert.Error(t, err, "注销后已签发的 Token 应失效")
+	assert.False(t, f.mr.Exists("user:profile:1"))
+}
+
+func TestAccountService_Deactivate_TwoFactorLockout(t *testing.T) {
+	f := newTestAccountService(t)
+	ctx := context.Background()
+	now := time.Now()
+	hashed, err := newHasher(t).Hash("password123")
+	require.NoError(t, err)
+	user := &domain.User{ID: 1, Password: hashed, TwoFactorSecret: "encrypted", TwoFactorConfirmedAt: &now}
+	f.users.EXPECT().FindByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+	f.codes.EXPECT().Consume(gomock.Any(), uint(1), gomock.Any()).Return(false, nil).Times(3)
+
+	// 错误的恢复码与错误的密码共用同一个按用户的计数
+	for range 2 {
+		err := f.svc.Deactivate(ctx, 1, service.DeactivateDTO{Code: "AAAA-BBBB"})
+		assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+	}
+	err = f.svc.Deactivate(ctx, 1, service.DeactivateDTO{Code: "AAAA-BBBB"})
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+
+	err = f.svc.Deactivate(ctx, 1, service.DeactivateDTO{CurrentPassword: "password123"})
+	assert.ErrorIs(t, err, service.ErrAccountLocked, "锁定期间正确的密码也不能注销")
+}
+
+func TestAccountService_Restore(t *testing.T) {
+	f := newTestAccountService(t)
+	f.users.EXPECT().Restore(gomock.Any(), uint(1)).Return(true, nil)
+	f.users.EXPECT().Restore(gomock.Any(), uint(2)).Return(false, nil)
+
+	require.NoError(t, f.svc.Restore(context.Background(), 1))
+	assert.ErrorIs(t, f.svc.Restore(context.Background(), 2), service.ErrUserNotFound)
+}
+
+func TestAccountService_Purge(t *testing.T) {
+	f := newTestAccountService(t)
+	ctx := context.Background()
+	before := time.Now().Add(-30 * 24 * time.Hour)
+
+	for _, id := range []uint{1, 2} {
+		_, err := f.permissions.AssignRole(service.RoleAssignment{Subject: service.UserSubject(id), Role: "admin"})
+		require.NoError(t, err)
+		require.NoError(t, f.mr.Set(fmt.Sprintf("user:profile:%d", id), "{}"))
+	}
+	// 清除之后仍有管理员，否则最后一个管理员的角色分配会被保留
+	_, err := f.permissions.AssignRole(service.RoleAssignment{Subject: service.UserSubject(3), Role: "admin"})
+	require.NoError(t, err)
+
+	// 用户相关的 Token 代数、登录限制和重发冷却
+	require.NoError(t, f.tokens.RevokeAll(ctx, 1))
+	for _, key := range []string{"login:fail:user:1", "login:lock:user:1", "login:fail:email:a@example.com", "login:lock:email:a@example.com", "email:verify:resend:1"} {
+		require.NoError(t, f.mr.Set(key, "1"))
+	}
+
+	f.users.EXPECT().FindDeletedBefore(gomock.Any(), before, uint(0), gomock.Any()).Return([]domain.User{{ID: 1, Email: "A@example.com"}, {ID: 2}}, nil)
+	f.users.EXPECT().Anonymize(gomock.Any(), uint(1)).Return(nil)
+	f.users.EXPECT().Anonymize(gomock.Any(), uint(2)).Return(nil)
+	f.users.EXPECT().ForceDelete(gomock.Any(), uint(1)).Return(true, nil)
+	// 2 号用户仍被其他业务表引用，只保留匿名化后的记录
+	f.users.EXPECT().ForceDelete(gomock.Any(), uint(2)).Return(false, errs.ErrConflict)
+
+	result, err := f.svc.Purge(ctx, before)
+	require.NoError(t, err)
+	assert.Equal(t, &service.PurgeResult{Deleted: 1, Anonymized: 1}, result)
+
+	for _, id := range []uint{1, 2} {
+		roles, err := f.permissions.RolesFor(service.UserSubject(id))
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+		assert.False(t, f.mr.Exists(fmt.Sprintf("user:profile:%d", id)))
+	}
+	for _, key := range []string{"login:fail:user:1", "login:lock:user:1", "login:fail:email:a@example.com", "login:lock:email:a@example.com", "email:verify:resend:1"} {
+		assert.False(t, f.mr.Exists(key), key)
+	}
+	// 代数不能立即删除，否则尚未过期的旧 Token 会重新生效
+	gen, err := f.mr.Get("auth:user_gen:1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", gen)
+	assert.Positive(t, f.mr.TTL("auth:user_gen:1"))
+}
@@ -0,0 +1,77 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-artisan/internal/domain"
+	"go-artisan/internal/domain/mocks"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/errs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAPIKeyService_CreateAndAuthenticate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockAPIKeyRepository(ctrl)
+	svc := service.NewAPIKeyService(repo)
+	ctx := context.Background()
+
+	// 1. 创建：明文只返回一次，库里是哈希，prefix 可用于辨认
+	var stored *domain.APIKey
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k *domain.APIKey) error {
+		stored = k
+		k.ID = 1
+		return nil
+	})
+	created, err := svc.Create(ctx, 7, service.CreateAPIKeyDTO{Name: "ci", Scopes: []string{"orders:read"}})
+	require.NoError(t, err)
+	assert.True(t, service.IsAPIKey(created.Key))
+	assert.True(t, strings.HasPrefix(created.Key, stored.Prefix+"_"))
+	assert.NotContains(t, stored.KeyHash, created.Key)
+
+	// 2. 认证成功并记录最近使用时间
+	repo.EXPECT().FindByHash(gomock.Any(), stored.KeyHash).Return(stored, nil)
+	repo.EXPECT().TouchLastUsed(gomock.Any(), uint(1), gomock.Any()).Return(nil)
+	key, err := svc.Authenticate(ctx, created.Key)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), key.UserID)
+	assert.NotNil(t, key.LastUsedAt)
+
+	// 3. 一分钟内再次使用不重复写库
+	repo.EXPECT().FindByHash(gomock.Any(), stored.KeyHash).Return(key, nil)
+	_, err = svc.Authenticate(ctx, created.Key)
+	require.NoError(t, err)
+	// 4. scope 格式不对时不写库，提示中带上出错的 scope
+	_, err = svc.Create(ctx, 7, service.CreateAPIKeyDTO{Name: "ci", Scopes: []string{"Orders:Read"}})
+	assert.ErrorIs(t, err, service.ErrInvalidScope)
+	var e *errs.Error
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, "scopes", e.Field)
+	assert.Contains(t, e.Message, `"Orders:Read"`)
+}
+
+func TestAPIKeyService_Authenticate_Rejects(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mocks.NewMockAPIKeyRepository(ctrl)
+	svc := service.NewAPIKeyService(repo)
+	ctx := context.Background()
+
+	// JWT 之类不带前缀的串直接拒绝，不查库
+	_, err := svc.Authenticate(ctx, "eyJhbGciOiJIUzI1NiJ9.e30.x")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	repo.EXPECT().FindByHash(gomock.Any(), gomock.Any()).Return(nil, errs.ErrNotFound)
+	_, err = svc.Authenticate(ctx, "ga_deadbeef_unknown")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+
+	expired := time.Now().Add(-time.Hour)
+	repo.EXPECT().FindByHash(gomock.Any(), gomock.Any()).Return(&domain.APIKey{ID: 2, ExpiresAt: &expired}, nil)
+	_, err = svc.Authenticate(ctx, "ga_deadbeef_expired")
+	assert.ErrorIs(t, err, service.ErrInvalidAPIKey)
+}
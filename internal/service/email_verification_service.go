@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/mail"
+	"go-artisan/pkg/urlsign"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// VerifyEmailPath 验证链接指向的接口
+const VerifyEmailPath = "/api/email/verify"
+
+var (
+	// ErrInvalidVerificationLink 链接被篡改、已过期或对应的邮箱已变更
+	ErrInvalidVerificationLink = errs.Forbidden(40301, "invalid or expired verification link")
+	// ErrVerificationThrottled 重发验证邮件过于频繁
+	ErrVerificationThrottled = errs.RateLimited(42901, "verification email already sent, please try again later")
+	// ErrAlreadyVerified 邮箱已验证，无需重发
+	ErrAlreadyVerified = errs.Conflict(40905, "email address already verified")
+)
+
+// EmailVerificationService 注册邮箱验证
+//
+// 验证链接是无状态的签名链接 (不落库)：
+//
+//	/api/email/verify?id=<uid>&hash=<sha256(email)>&expires=<unix>&signature=<hmac>
+//
+// hash 保证用户修改邮箱后旧链接自动失效
+type EmailVerificationService struct {
+	users  domain.UserRepository
+	mailer mail.Mailer
+	signer *urlsign.Signer
+	config *config.Config
+	redis  *redis.Client
+}
+
+func NewEmailVerificationService(users domain.UserRepository, mailer mail.Mailer, cfg *config.Config, rdb *redis.Client) *EmailVerificationService {
+	return &EmailVerificationService{
+		users:  users,
+		mailer: mailer,
+		signer: urlsign.New(cfg.App.Key),
+		config: cfg,
+		redis:  rdb,
+	}
+}
+
+// Send 给用户发送验证邮件 (注册成功后调用)
+func (s *EmailVerificationService) Send(ctx context.Context, user *domain.User) error {
+	ttl := s.config.Auth.VerificationTTL
+	params := s.signer.Sign(url.Values{
+		"id":   {strconv.FormatUint(uint64(user.ID), 10)},
+		"hash": {emailHash(user.Email)},
+	}, time.Now().Add(ttl))
+	link := s.config.App.URL + VerifyEmailPath + "?" + params.Encode()
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below within %s:\n\n%s\n\n"+
+			"If you did not create an account, no further action is required.\n", user.Name, ttl, link),
+	})
+}
+
+// Resend 重新发送验证邮件，同一用户在冷却期内只能发送一次
+func (s *EmailVerificationService) Resend(ctx context.Context, userID uint) error {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.IsVerified() {
+		return ErrAlreadyVerified
+	}
+
+	cooldown := s.config.Auth.VerificationResendCooldown
+	if cooldown > 0 {
+		key := emailResendKey(userID)
+		ok, err := s.redis.SetNX(ctx, key, 1, cooldown).Result()
+		if err != nil {
+			return fmt.Errorf("failed to throttle verification email: %w", err)
+		}
+		if !ok {
+			ttl, _ := s.redis.PTTL(ctx, key).Result()
+			return ErrVerificationThrottled.RetryIn(ttl)
+		}
+	}
+
+	return s.Send(ctx, user)
+}
+
+// Verify 校验链接参数并把邮箱标记为已验证；重复点击同一链接视为成功
+func (s *EmailVerificationService) Verify(ctx context.Context, params url.Values) (*domain.User, error) {
+	if err := s.signer.Verify(params, time.Now()); err != nil {
+		return nil, ErrInvalidVerificationLink
+	}
+
+	id, err := strconv.ParseUint(params.Get("id"), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidVerificationLink
+	}
+	user, err := s.users.FindByID(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, ErrInvalidVerificationLink
+		}
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(params.Get("hash")), []byte(emailHash(user.Email))) != 1 {
+		return nil, ErrInvalidVerificationLink
+	}
+
+	if user.IsVerified() {
+		return user, nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	// verified 中间件读的是资料缓存，必须清掉
+	s.redis.Del(ctx, profileCacheKey(user.ID))
+	return user, nil
+}
+
+func emailHash(email string) string {
+	sum := sha256.Sum256([]byte(normalizeEmail(email)))
+	return hex.EncodeToString(sum[:])
+}
+
+func emailResendKey(userID uint) string {
+	return fmt.Sprintf("email:verify:resend:%d", userID)
+}
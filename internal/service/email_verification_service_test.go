@@ -0,0 +1,96 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/domain/mocks"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/errs"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newVerificationService(t *testing.T, users domain.UserRepository, mailer *captureMailer) *service.EmailVerificationService {
+	cfg := &config.Config{
+		App: config.AppConfig{URL: "http://localhost:8080", Key: "test-app-key"},
+		Auth: config.AuthConfig{
+			VerificationTTL:            time.Hour,
+			VerificationResendCooldown: time.Minute,
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	return service.NewEmailVerificationService(users, mailer, cfg, rdb)
+}
+
+// verificationQuery 从邮件正文的链接里取出查询参数
+func verificationQuery(t *testing.T, body string) url.Values {
+	i := strings.Index(body, "http://")
+	require.NotEqual(t, -1, i, "verification link not found in mail body")
+	link, err := url.Parse(strings.Fields(body[i:])[0])
+	require.NoError(t, err)
+	assert.Equal(t, service.VerifyEmailPath, link.Path)
+	return link.Query()
+}
+
+func TestEmailVerificationService_SendAndVerify(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	mailer := &captureMailer{}
+	svc := newVerificationService(t, users, mailer)
+	ctx := context.Background()
+
+	user := &domain.User{ID: 7, Name: "Dan", Email: "dan@example.com"}
+	require.NoError(t, svc.Send(ctx, user))
+	require.Len(t, mailer.sent, 1)
+	query := verificationQuery(t, mailer.sent[0].Body)
+
+	// 篡改 id 后签名失效
+	tampered := url.Values{}
+	for k, v := range query {
+		tampered[k] = v
+	}
+	tampered.Set("id", "8")
+	_, err := svc.Verify(ctx, tampered)
+	assert.ErrorIs(t, err, service.ErrInvalidVerificationLink)
+
+	// 原链接验证成功
+	users.EXPECT().FindByID(gomock.Any(), uint(7)).Return(user, nil)
+	users.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	verified, err := svc.Verify(ctx, query)
+	require.NoError(t, err)
+	assert.True(t, verified.IsVerified())
+
+	// 邮箱变更后旧链接失效
+	users.EXPECT().FindByID(gomock.Any(), uint(7)).Return(&domain.User{ID: 7, Email: "new@example.com"}, nil)
+	_, err = svc.Verify(ctx, query)
+	assert.ErrorIs(t, err, service.ErrInvalidVerificationLink)
+}
+
+func TestEmailVerificationService_Resend_Throttled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	mailer := &captureMailer{}
+	svc := newVerificationService(t, users, mailer)
+	ctx := context.Background()
+
+	users.EXPECT().FindByID(gomock.Any(), uint(7)).Return(&domain.User{ID: 7, Email: "dan@example.com"}, nil).Times(2)
+
+	require.NoError(t, svc.Resend(ctx, 7))
+
+	err := svc.Resend(ctx, 7)
+	var throttled *errs.Error
+	require.ErrorAs(t, err, &throttled)
+	assert.ErrorIs(t, err, service.ErrVerificationThrottled)
+	assert.Greater(t, throttled.RetryAfter, time.Duration(0))
+	assert.Len(t, mailer.sent, 1)
+}
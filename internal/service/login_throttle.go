@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/pkg/errs"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultMaxLoginDelay max_delay 未配置时的渐进延迟上限
+const defaultMaxLoginDelay = 3 * time.Second
+
+// 锁定错误返回时通过 RetryIn 带上剩余锁定时间，渲染为 Retry-After
+var (
+	// ErrAccountLocked 同一账户失败次数过多，账户被临时锁定
+	ErrAccountLocked = errs.RateLimited(42902, "account temporarily locked due to too many failed login attempts")
+	// ErrTooManyAttempts 同一 IP 失败次数过多，IP 被临时封禁
+	ErrTooManyAttempts = errs.RateLimited(42903, "too many failed login attempts, please try again later")
+)
+
+// LoginThrottle 登录防爆破
+//
+// Redis 结构:
+//
+//	login:fail:email:<email>  失败次数  TTL = window
+//	login:fail:ip:<ip>        失败次数  TTL = window
+//	login:lock:email:<email>  锁定标记  TTL = lockout_duration
+//	login:lock:ip:<ip>        锁定标记  TTL = lockout_duration
+//	login:fail:user:<id>      已登录用户确认当前密码的失败次数  TTL = window
+//	login:lock:user:<id>      锁定标记  TTL = lockout_duration
+type LoginThrottle struct {
+	config *config.Config
+	redis  *redis.Client
+}
+
+func NewLoginThrottle(cfg *config.Config, rdb *redis.Client) *LoginThrottle {
+	return &LoginThrottle{config: cfg, redis: rdb}
+}
+
+// Check 登录前检查账户和 IP 是否处于锁定状态
+func (t *LoginThrottle) Check(ctx context.Context, email, ip string) error {
+	pipe := t.redis.Pipeline()
+	accountTTL := pipe.PTTL(ctx, lockKey("email", normalizeEmail(email)))
+	ipTTL := pipe.PTTL(ctx, lockKey("ip", ip))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to check login lockout: %w", err)
+	}
+
+	// 不存在的 key PTTL 返回负数
+	if ttl := ipTTL.Val(); ttl > 0 {
+		return ErrTooManyAttempts.RetryIn(ttl)
+	}
+	if ttl := accountTTL.Val(); ttl > 0 {
+		return ErrAccountLocked.RetryIn(ttl)
+	}
+	return nil
+}
+
+// RecordFailure 记录一次失败，达到阈值时锁定；返回本次应施加的渐进延迟
+// 本次失败触发锁定时同时返回 ErrAccountLocked / ErrTooManyAttempts
+func (t *LoginThrottle) RecordFailure(ctx context.Context, email, ip string) (time.Duration, error) {
+	cfg := t.config.Auth.LoginThrottle
+	email = normalizeEmail(email)
+
+	pipe := t.redis.TxPipeline()
+	accountFails := pipe.Incr(ctx, failKey("email", email))
+	pipe.ExpireNX(ctx, failKey("email", email), cfg.Window)
+	ipFails := pipe.Incr(ctx, failKey("ip", ip))
+	pipe.ExpireNX(ctx, failKey("ip", ip), cfg.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	delay := t.delay(accountFails.Val())
+
+	if cfg.MaxIPAttempts > 0 && ipFails.Val() >= int64(cfg.MaxIPAttempts) {
+		if err := t.lock(ctx, "ip", ip); err != nil {
+			return delay, err
+		}
+		return delay, ErrTooManyAttempts.RetryIn(cfg.LockoutDuration)
+	}
+	if cfg.MaxAccountAttempts > 0 && accountFails.Val() >= int64(cfg.MaxAccountAttempts) {
+		if err := t.lock(ctx, "email", email); err != nil {
+			return delay, err
+		}
+		return delay, ErrAccountLocked.RetryIn(cfg.LockoutDuration)
+	}
+	return delay, nil
+}
+
+// CheckUser 已登录用户确认当前密码 (改邮箱、改密码、注销账户) 前检查是否处于锁定状态
+func (t *LoginThrottle) CheckUser(ctx context.Context, userID uint) error {
+	ttl, err := t.redis.PTTL(ctx, lockKey("user", userKey(userID))).Result()
+	if err != nil {
+		return fmt.Errorf("failed to check login lockout: %w", err)
+	}
+	if ttl > 0 {
+		return ErrAccountLocked.RetryIn(ttl)
+	}
+	return nil
+}
+
+// RecordUserFailure 当前密码输错一次，按用户计数，阈值与锁定时长同账户登录
+// 拿到会话的攻击者不能借这些接口绕过登录限制无限次猜密码
+func (t *LoginThrottle) RecordUserFailure(ctx context.Context, userID uint) (time.Duration, error) {
+	cfg := t.config.Auth.LoginThrottle
+	id := userKey(userID)
+
+	pipe := t.redis.TxPipeline()
+	fails := pipe.Incr(ctx, failKey("user", id))
+	pipe.ExpireNX(ctx, failKey("user", id), cfg.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	delay := t.delay(fails.Val())
+	if cfg.MaxAccountAttempts > 0 && fails.Val() >= int64(cfg.MaxAccountAttempts) {
+		if err := t.lock(ctx, "user", id); err != nil {
+			return delay, err
+		}
+		return delay, ErrAccountLocked.RetryIn(cfg.LockoutDuration)
+	}
+	return delay, nil
+}
+
+// ResetUser 当前密码确认成功后清空失败计数
+func (t *LoginThrottle) ResetUser(ctx context.Context, userID uint) error {
+	return t.redis.Del(ctx, failKey("user", userKey(userID))).Err()
+}
+
+// Reset 登录成功后清空账户失败计数 (IP 计数保留，防止攻击者用自己的账户洗白 IP)
+func (t *LoginThrottle) Reset(ctx context.Context, email string) error {
+	return t.redis.Del(ctx, failKey("email", normalizeEmail(email))).Err()
+}
+
+// Unlock 管理员手动解锁账户和/或 IP，userID 不为 0 时同时清除按用户的计数与锁定
+func (t *LoginThrottle) Unlock(ctx context.Context, userID uint, email, ip string) error {
+	var keys []string
+	if userID != 0 {
+		id := userKey(userID)
+		keys = append(keys, failKey("user", id), lockKey("user", id))
+	}
+	if email != "" {
+		email = normalizeEmail(email)
+		keys = append(keys, failKey("email", email), lockKey("email", email))
+	}
+	if ip != "" {
+		keys = append(keys, failKey("ip", ip), lockKey("ip", ip))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := t.redis.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to unlock login: %w", err)
+	}
+	return nil
+}
+
+// Forget 清除用户的全部计数与锁定 (账户被清除后不再需要)
+func (t *LoginThrottle) Forget(ctx context.Context, userID uint, email string) error {
+	id, email := userKey(userID), normalizeEmail(email)
+	keys := []string{failKey("user", id), lockKey("user", id), failKey("email", email), lockKey("email", email)}
+	if err := t.redis.Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+	return nil
+}
+
+// Wait 按渐进延迟等待，请求被取消时立即返回
+func (t *LoginThrottle) Wait(ctx context.Context, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// delay 第 n 次失败的延迟：base * 2^(n-1)，不超过 max
+// max 未配置时使用 defaultMaxLoginDelay：延迟由攻击者的失败次数决定，不封顶会让请求一直占着 goroutine
+func (t *LoginThrottle) delay(failures int64) time.Duration {
+	cfg := t.config.Auth.LoginThrottle
+	if cfg.BaseDelay <= 0 || failures <= 0 {
+		return 0
+	}
+	limit := cfg.MaxDelay
+	if limit <= 0 {
+		limit = defaultMaxLoginDelay
+	}
+	d := cfg.BaseDelay
+	for i := int64(1); i < failures && d < limit; i++ {
+		if d > limit/2 { // 再翻倍就超过上限 (也避免 limit 很大时溢出)
+			d = limit
+		} else {
+			d *= 2
+		}
+	}
+	return min(d, limit)
+}
+
+func (t *LoginThrottle) lock(ctx context.Context, scope, value string) error {
+	if err := t.redis.Set(ctx, lockKey(scope, value), 1, t.config.Auth.LoginThrottle.LockoutDuration).Err(); err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+// normalizeEmail 防止通过大小写变化绕过账户计数
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func userKey(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+func failKey(scope, value string) string {
+	return "login:fail:" + scope + ":" + value
+}
+
+func lockKey(scope, value string) string {
+	return "login:lock:" + scope + ":" + value
+}
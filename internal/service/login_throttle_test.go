@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/service"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginThrottle_Delay(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		baseDelay time.Duration
+		maxDelay  time.Duration
+		want      []time.Duration
+	}{
+		{"有上限", 100 * time.Millisecond, 300 * time.Millisecond, []time.Duration{100, 200, 300, 300}},
+		{"max_delay 未配置时使用默认上限", time.Second, 0, []time.Duration{1000, 2000, 3000, 3000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+			throttle := service.NewLoginThrottle(&config.Config{Auth: config.AuthConfig{
+				LoginThrottle: config.LoginThrottleConfig{
+					Window:    time.Minute,
+					BaseDelay: tt.baseDelay,
+					MaxDelay:  tt.maxDelay,
+				},
+			}}, rdb)
+
+			for _, want := range tt.want {
+				delay, err := throttle.RecordFailure(ctx, "a@example.com", "10.0.0.1")
+				require.NoError(t, err)
+				assert.Equal(t, want*time.Millisecond, delay)
+			}
+		})
+	}
+}
@@ -0,0 +1,625 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-artisan/internal/domain"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/errs"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OAuth2 授权类型
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+// authorizationCodeTTL 授权码有效期，RFC 6749 4.1.2 建议不超过 10 分钟
+const authorizationCodeTTL = 10 * time.Minute
+
+// OAuth2 错误码 (RFC 6749 4.1.2.1 / 5.2)
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAccessDenied            = "access_denied"
+)
+
+var (
+	ErrOAuthClientNotFound = errs.NotFound(40404, "oauth client not found")
+	// ErrInvalidClientMetadata 注册客户端时参数不合法，返回时用 invalidClientMetadata 带上具体原因
+	ErrInvalidClientMetadata = errs.Validation(42208, "invalid client metadata")
+)
+
+// OAuthError 按 RFC 6749 格式返回给客户端的错误
+// redirectURI 不为空时错误可以安全地重定向回客户端，否则只能直接展示给用户
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+
+	redirectURI string
+	state       string
+}
+
+func (e *OAuthError) Error() string { return e.Code + ": " + e.Description }
+
+// RedirectURL 携带错误信息的回调地址，不可重定向的错误返回空串
+func (e *OAuthError) RedirectURL() string {
+	if e.redirectURI == "" {
+		return ""
+	}
+	params := url.Values{"error": {e.Code}}
+	if e.Description != "" {
+		params.Set("error_description", e.Description)
+	}
+	if e.state != "" {
+		params.Set("state", e.state)
+	}
+	return appendQuery(e.redirectURI, params)
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// RegisterClientDTO 注册客户端的输入
+type RegisterClientDTO struct {
+	Name         string
+	RedirectURIs []string
+	GrantTypes   []string // 为空时默认 authorization_code + refresh_token
+	Scopes       []string
+	Confidential bool
+}
+
+// RegisteredClient 注册结果，Secret 明文只在这里出现一次 (公开客户端为空)
+type RegisteredClient struct {
+	*domain.OAuthClient
+	Secret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizeRequest /oauth/authorize 的参数 (GET 走 query，POST 走 JSON)
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type"`
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// AuthorizationPrompt 校验通过的授权请求，前端据此渲染授权确认页
+type AuthorizationPrompt struct {
+	Client      *domain.OAuthClient `json:"client"`
+	Scopes      []string            `json:"scopes"`
+	RedirectURI string              `json:"redirect_uri"`
+	State       string              `json:"state,omitempty"`
+}
+
+// TokenRequest /oauth/token 的参数 (application/x-www-form-urlencoded)
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+}
+
+// OAuthToken Token 端点响应 (RFC 6749 5.1)
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Introspection 令牌自省响应 (RFC 7662 2.2)，无效 Token 只返回 active=false
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+}
+
+// authorizationCode 授权码在 Redis 中保存的内容
+type authorizationCode struct {
+	ClientID      string `json:"client_id"`
+	UserID        uint   `json:"user_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"code_challenge"`
+}
+
+// OAuthService OAuth2 授权服务器 (授权码 + PKCE、client_credentials、自省与吊销)
+// Access/Refresh Token 复用 TokenService，OAuth2 签发的 Token 带 client_id 且只能由同一客户端轮换
+//
+// Redis 结构:
+//
+//	oauth:code:<sha256>   String JSON{client_id, user_id, redirect_uri, scope, code_challenge}  TTL = 10m
+//
+// 授权码用 GETDEL 读取，保证只能兑换一次；所有客户端都必须使用 PKCE (S256)
+type OAuthService struct {
+	clients domain.OAuthClientRepository
+	tokens  *TokenService
+	redis   *redis.Client
+}
+
+func NewOAuthService(clients domain.OAuthClientRepository, tokens *TokenService, rdb *redis.Client) *OAuthService {
+	return &OAuthService{clients: clients, tokens: tokens, redis: rdb}
+}
+
+// RegisterClient 注册客户端
+func (s *OAuthService) RegisterClient(ctx context.Context, req RegisterClientDTO) (*RegisteredClient, error) {
+	grants := req.GrantTypes
+	if len(grants) == 0 {
+		grants = []string{GrantAuthorizationCode, GrantRefreshToken}
+	}
+	for _, g := range grants {
+		switch g {
+		case GrantAuthorizationCode, GrantRefreshToken:
+		case GrantClientCredentials:
+			if !req.Confidential {
+				return nil, invalidClientMetadata("public clients cannot use client_credentials")
+			}
+		default:
+			return nil, invalidClientMetadata("unsupported grant type %q", g)
+		}
+	}
+	if slices.Contains(grants, GrantAuthorizationCode) && len(req.RedirectURIs) == 0 {
+		return nil, invalidClientMetadata("authorization_code requires at least one redirect uri")
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return nil, err
+		}
+	}
+	for _, scope := range req.Scopes {
+		// 第三方客户端不能拿到与第一方登录等价的 * 权限
+		if scope == auth.ScopeAll || !auth.ValidScope(scope) {
+			return nil, invalidClientMetadata("invalid scope %q", scope)
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate client id: %w", err)
+	}
+	client := &domain.OAuthClient{
+		ClientID:     hex.EncodeToString(id),
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		GrantTypes:   grants,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	}
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+	if client.Scopes == nil {
+		client.Scopes = []string{}
+	}
+
+	var secret string
+	if req.Confidential {
+		var err error
+		if secret, err = randomToken(); err != nil {
+			return nil, err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := s.clients.Create(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to store oauth client: %w", err)
+	}
+	return &RegisteredClient{OAuthClient: client, Secret: secret}, nil
+}
+
+// ListClients 列出全部客户端 (不含 secret)
+func (s *OAuthService) ListClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	return s.clients.List(ctx)
+}
+
+// DeleteClient 删除客户端
+// 已签发的 Access Token 在过期前仍然有效，Refresh Token 因客户端无法认证而立即不可用
+func (s *OAuthService) DeleteClient(ctx context.Context, clientID string) error {
+	ok, err := s.clients.Delete(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// Authorize 校验授权请求 (GET /oauth/authorize)
+// client_id 或 redirect_uri 有误时不能重定向 (防止开放重定向)，其余错误都会带上回调地址
+func (s *OAuthService) Authorize(ctx context.Context, req AuthorizeRequest) (*AuthorizationPrompt, error) {
+	client, err := s.clients.FindByClientID(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, oauthError(OAuthInvalidClient, "unknown client")
+		}
+		return nil, err
+	}
+	if req.RedirectURI == "" || !client.AllowsRedirect(req.RedirectURI) {
+		return nil, oauthError(OAuthInvalidRequest, "redirect_uri is not registered for this client")
+	}
+
+	fail := func(code, description string) error {
+		return &OAuthError{Code: code, Description: description, redirectURI: req.RedirectURI, state: req.State}
+	}
+	if req.ResponseType != "code" {
+		return nil, fail(OAuthUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if !client.AllowsGrant(GrantAuthorizationCode) {
+		return nil, fail(OAuthUnauthorizedClient, "client is not allowed to use authorization_code")
+	}
+	if req.CodeChallenge == "" {
+		return nil, fail(OAuthInvalidRequest, "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != "S256" {
+		return nil, fail(OAuthInvalidRequest, "code_challenge_method must be S256")
+	}
+	scopes, ok := resolveScopes(client, req.Scope)
+	if !ok {
+		return nil, fail(OAuthInvalidScope, "requested scope exceeds what the client may request")
+	}
+
+	return &AuthorizationPrompt{Client: client, Scopes: scopes, RedirectURI: req.RedirectURI, State: req.State}, nil
+}
+
+// Approve 用户确认或拒绝授权 (POST /oauth/authorize)，返回需要跳转的回调地址
+func (s *OAuthService) Approve(ctx context.Context, userID uint, req AuthorizeRequest, approved bool) (string, error) {
+	prompt, err := s.Authorize(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !approved {
+		return (&OAuthError{
+			Code:        OAuthAccessDenied,
+			Description: "the user denied the request",
+			redirectURI: prompt.RedirectURI,
+			state:       prompt.State,
+		}).RedirectURL(), nil
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(authorizationCode{
+		ClientID:      prompt.Client.ClientID,
+		UserID:        userID,
+		RedirectURI:   prompt.RedirectURI,
+		Scope:         auth.JoinScope(prompt.Scopes),
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.redis.Set(ctx, authorizationCodeKey(code), data, authorizationCodeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store authorization code: %w", err)
+	}
+
+	params := url.Values{"code": {code}}
+	if prompt.State != "" {
+		params.Set("state", prompt.State)
+	}
+	return appendQuery(prompt.RedirectURI, params), nil
+}
+
+// Token 令牌端点 (POST /oauth/token)
+func (s *OAuthService) Token(ctx context.Context, req TokenRequest) (*OAuthToken, error) {
+	switch req.GrantType {
+	case GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials:
+	case "":
+		return nil, oauthError(OAuthInvalidRequest, "grant_type is required")
+	default:
+		return nil, oauthError(OAuthUnsupportedGrantType, "unsupported grant_type "+strconv.Quote(req.GrantType))
+	}
+
+	client, err := s.AuthenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, oauthError(OAuthUnauthorizedClient, "client is not allowed to use "+req.GrantType)
+	}
+
+	var pair *TokenPair
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		pair, err = s.exchangeCode(ctx, client, req)
+	case GrantRefreshToken:
+		// 不支持通过 scope 参数缩小范围，始终沿用原授权
+		pair, err = s.tokens.RefreshForClient(ctx, req.RefreshToken, client.ClientID)
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			err = oauthError(OAuthInvalidGrant, err.Error())
+		}
+	case GrantClientCredentials:
+		scopes, ok := resolveScopes(client, req.Scope)
+		if !ok {
+			return nil, oauthError(OAuthInvalidScope, "requested scope exceeds what the client may request")
+		}
+		pair, err = s.tokens.IssueClientToken(client.ClientID, scopes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthToken{
+		AccessToken:  pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    pair.ExpiresIn,
+		RefreshToken: pair.RefreshToken,
+		Scope:        pair.Scope,
+	}, nil
+}
+
+// Introspect 令牌自省 (POST /oauth/introspect)，只有机密客户端 (资源服务器) 可以调用
+// 客户端只能自省发给自己的 Token，第一方登录和其他客户端的 Token 一律返回 active=false
+func (s *OAuthService) Introspect(ctx context.Context, clientID, clientSecret, token, hint string) (*Introspection, error) {
+	client, err := s.AuthenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential {
+		return nil, oauthError(OAuthUnauthorizedClient, "only confidential clients may introspect tokens")
+	}
+
+	for _, kind := range tokenLookupOrder(hint) {
+		if kind == GrantRefreshToken {
+			info, err := s.tokens.LookupRefresh(ctx, token)
+			if errors.Is(err, ErrInvalidRefreshToken) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if info.ClientID != client.ClientID {
+				return &Introspection{Active: false}, nil
+			}
+			return &Introspection{
+				Active:    true,
+				Scope:     info.Scope,
+				ClientID:  info.ClientID,
+				Subject:   subject(info.UserID),
+				TokenType: "refresh_token",
+				ExpiresAt: info.ExpiresAt.Unix(),
+			}, nil
+		}
+
+		claims, err := s.tokens.Authenticate(ctx, token)
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if claims.ClientID != client.ClientID {
+			return &Introspection{Active: false}, nil
+		}
+		return &Introspection{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Subject:   subject(claims.UserID),
+			TokenType: "Bearer",
+			ExpiresAt: claims.ExpiresAt.Unix(),
+			IssuedAt:  claims.IssuedAt.Unix(),
+			Issuer:    claims.Issuer,
+		}, nil
+	}
+	return &Introspection{Active: false}, nil
+}
+
+// Revoke 吊销令牌 (POST /oauth/revoke)
+// 客户端只能吊销发给自己的 Token；无效或不属于该客户端的 Token 按 RFC 7009 2.2 同样视为成功
+func (s *OAuthService) Revoke(ctx context.Context, clientID, clientSecret, token, hint string) error {
+	client, err := s.AuthenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range tokenLookupOrder(hint) {
+		if kind == GrantRefreshToken {
+			info, err := s.tokens.LookupRefresh(ctx, token)
+			if errors.Is(err, ErrInvalidRefreshToken) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if info.ClientID != client.ClientID {
+				return nil
+			}
+			return s.tokens.RevokeFamily(ctx, info.Family)
+		}
+
+		claims, err := s.tokens.Authenticate(ctx, token)
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenRevoked) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if claims.ClientID != client.ClientID {
+			return nil
+		}
+		return s.tokens.Revoke(ctx, claims)
+	}
+	return nil
+}
+
+// AuthenticateClient 认证客户端：机密客户端必须提供正确的 secret，公开客户端不能提供 secret
+func (s *OAuthService) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*domain.OAuthClient, error) {
+	if clientID == "" {
+		return nil, oauthError(OAuthInvalidClient, "client authentication failed")
+	}
+	client, err := s.clients.FindByClientID(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, oauthError(OAuthInvalidClient, "client authentication failed")
+		}
+		return nil, err
+	}
+
+	if client.Confidential {
+		// secret 是高熵随机串，SHA-256 足够；比较摘要避免时序攻击
+		if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+			return nil, oauthError(OAuthInvalidClient, "client authentication failed")
+		}
+	} else if clientSecret != "" {
+		return nil, oauthError(OAuthInvalidClient, "public clients must not send a client_secret")
+	}
+	return client, nil
+}
+
+// exchangeCode 授权码兑换 Token，校验客户端、redirect_uri 与 PKCE
+func (s *OAuthService) exchangeCode(ctx context.Context, client *domain.OAuthClient, req TokenRequest) (*TokenPair, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, oauthError(OAuthInvalidRequest, "code and code_verifier are required")
+	}
+
+	// GETDEL 原子地取出并删除，授权码只能使用一次
+	data, err := s.redis.GetDel(ctx, authorizationCodeKey(req.Code)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, oauthError(OAuthInvalidGrant, "authorization code is invalid or expired")
+		}
+		return nil, fmt.Errorf("failed to load authorization code: %w", err)
+	}
+	var code authorizationCode
+	if err := json.Unmarshal(data, &code); err != nil {
+		return nil, oauthError(OAuthInvalidGrant, "authorization code is invalid or expired")
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, oauthError(OAuthInvalidGrant, "authorization code was issued to another client or redirect_uri")
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError(OAuthInvalidGrant, "code_verifier does not match code_challenge")
+	}
+
+	return s.tokens.IssueForClient(ctx, code.UserID, client.ClientID, auth.ParseScope(code.Scope))
+}
+
+// resolveScopes 请求的 scope 必须在客户端允许范围内，未指定时授予客户端的全部 scope
+func resolveScopes(client *domain.OAuthClient, requested string) ([]string, bool) {
+	scopes := auth.ParseScope(requested)
+	if len(scopes) == 0 {
+		return client.Scopes, true
+	}
+	for _, scope := range scopes {
+		if scope == auth.ScopeAll || !auth.ValidScope(scope) || !auth.HasScope(client.Scopes, scope) {
+			return nil, false
+		}
+	}
+	return scopes, true
+}
+
+// verifyCodeChallenge PKCE S256：BASE64URL(SHA256(code_verifier)) == code_challenge (RFC 7636 4.6)
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// validateRedirectURI 回调地址必须是不带 fragment 的绝对地址 (RFC 8252 7)
+//
+//	https://app.example.com/callback     Web 应用
+//	http://127.0.0.1:8080/callback       原生应用的回环地址，http 只允许回环
+//	com.example.app:/oauth2redirect      原生应用的私有 scheme，必须是反向域名，避免 javascript: 等危险 scheme
+func validateRedirectURI(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return invalidClientMetadata("redirect uri %q must be an absolute URL without fragment", raw)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return invalidClientMetadata("redirect uri %q must use https", raw)
+		}
+		return nil
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return invalidClientMetadata("redirect uri %q must use https or a reverse-domain private scheme", raw)
+		}
+		return nil
+	}
+}
+
+// invalidClientMetadata 带上具体原因的 ErrInvalidClientMetadata，业务码不变
+func invalidClientMetadata(format string, args ...any) error {
+	return ErrInvalidClientMetadata.WithMessage("invalid client metadata: " + fmt.Sprintf(format, args...))
+}
+
+// tokenLookupOrder 按 token_type_hint 决定先按哪种 Token 查找 (RFC 7009 2.1)
+func tokenLookupOrder(hint string) []string {
+	if hint == GrantRefreshToken {
+		return []string{GrantRefreshToken, "access_token"}
+	}
+	return []string{"access_token", GrantRefreshToken}
+}
+
+// subject client_credentials 签发的 Token 没有用户
+func subject(userID uint) string {
+	if userID == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// appendQuery 在回调地址已有 query 的基础上追加参数
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, vs := range params {
+		q[k] = vs
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func authorizationCodeKey(code string) string {
+	return "oauth:code:" + hashToken(code)
+}
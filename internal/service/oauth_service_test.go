@@ -0,0 +1,272 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/domain/mocks"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/errs"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	testRedirectURI  = "https://app.example.com/callback"
+	testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r-wW1gFWFOEjXk"
+)
+
+func newTestOAuthService(t *testing.T) (*service.OAuthService, *service.TokenService, *mocks.MockOAuthClientRepository) {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret:     "test-secret",
+			TTL:        15 * time.Minute,
+			RefreshTTL: 24 * time.Hour,
+			Issuer:     "go-artisan",
+		},
+	}
+	tokens, err := service.NewTokenService(cfg, rdb)
+	require.NoError(t, err)
+
+	repo := mocks.NewMockOAuthClientRepository(gomock.NewController(t))
+	return service.NewOAuthService(repo, tokens, rdb), tokens, repo
+}
+
+// registerClient 注册客户端并让仓储按 client_id 返回它
+func registerClient(t *testing.T, svc *service.OAuthService, repo *mocks.MockOAuthClientRepository, req service.RegisterClientDTO) *service.RegisteredClient {
+	var stored *domain.OAuthClient
+	repo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, c *domain.OAuthClient) error {
+		stored = c
+		return nil
+	})
+	client, err := svc.RegisterClient(context.Background(), req)
+	require.NoError(t, err)
+	repo.EXPECT().FindByClientID(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, id string) (*domain.OAuthClient, error) {
+		if id == stored.ClientID {
+			return stored, nil
+		}
+		return nil, errs.ErrNotFound
+	}).AnyTimes()
+	return client
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func authorizeRequest(clientID string) service.AuthorizeRequest {
+	return service.AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "orders:read",
+		State:               "xyz",
+		CodeChallenge:       codeChallenge(testCodeVerifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+// approve 模拟用户同意授权，返回回调中的授权码
+func approve(t *testing.T, svc *service.OAuthService, req service.AuthorizeRequest) string {
+	redirectTo, err := svc.Approve(context.Background(), 7, req, true)
+	require.NoError(t, err)
+	u, err := url.Parse(redirectTo)
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", u.Query().Get("state"))
+	return u.Query().Get("code")
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *service.OAuthError
+	require.True(t, errors.As(err, &oauthErr), "want *OAuthError, got %v", err)
+	assert.Equal(t, code, oauthErr.Code)
+}
+
+func TestOAuthService_AuthorizationCode(t *testing.T) {
+	ctx := context.Background()
+	svc, tokens, repo := newTestOAuthService(t)
+	client := registerClient(t, svc, repo, service.RegisterClientDTO{
+		Name:         "SPA",
+		RedirectURIs: []string{testRedirectURI},
+		Scopes:       []string{"orders:read", "orders:write"},
+	})
+	assert.Empty(t, client.Secret, "公开客户端没有 secret")
+
+	exchange := func(code, verifier string) (*service.OAuthToken, error) {
+		return svc.Token(ctx, service.TokenRequest{
+			GrantType:    service.GrantAuthorizationCode,
+			Code:         code,
+			RedirectURI:  testRedirectURI,
+			CodeVerifier: verifier,
+			ClientID:     client.ClientID,
+		})
+	}
+
+	t.Run("授权码 + PKCE 换取绑定客户端的 Token，授权码只能用一次", func(t *testing.T) {
+		code := approve(t, svc, authorizeRequest(client.ClientID))
+
+		token, err := exchange(code, testCodeVerifier)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer", token.TokenType)
+		assert.Equal(t, "orders:read", token.Scope)
+		assert.NotEmpty(t, token.RefreshToken)
+
+		claims, err := tokens.Authenticate(ctx, token.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, uint(7), claims.UserID)
+		assert.Equal(t, client.ClientID, claims.ClientID)
+
+		_, err = exchange(code, testCodeVerifier)
+		assertOAuthError(t, err, service.OAuthInvalidGrant)
+	})
+
+	t.Run("code_verifier 不匹配", func(t *testing.T) {
+		code := approve(t, svc, authorizeRequest(client.ClientID))
+		_, err := exchange(code, strings.Repeat("a", 43))
+		assertOAuthError(t, err, service.OAuthInvalidGrant)
+	})
+
+	t.Run("Refresh Token 只能由同一客户端通过 OAuth 轮换", func(t *testing.T) {
+		token, err := exchange(approve(t, svc, authorizeRequest(client.ClientID)), testCodeVerifier)
+		require.NoError(t, err)
+
+		_, err = tokens.Refresh(ctx, token.RefreshToken)
+		assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+
+		refreshed, err := svc.Token(ctx, service.TokenRequest{
+			GrantType:    service.GrantRefreshToken,
+			RefreshToken: token.RefreshToken,
+			ClientID:     client.ClientID,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "orders:read", refreshed.Scope)
+	})
+
+	t.Run("未注册的 redirect_uri 不能重定向", func(t *testing.T) {
+		req := authorizeRequest(client.ClientID)
+		req.RedirectURI = "https://evil.example.com/callback"
+		_, err := svc.Authorize(ctx, req)
+		var oauthErr *service.OAuthError
+		require.ErrorAs(t, err, &oauthErr)
+		assert.Empty(t, oauthErr.RedirectURL())
+	})
+
+	t.Run("缺少 PKCE 或超出客户端 scope 时重定向回客户端", func(t *testing.T) {
+		req := authorizeRequest(client.ClientID)
+		req.CodeChallenge = ""
+		_, err := svc.Authorize(ctx, req)
+		assertOAuthError(t, err, service.OAuthInvalidRequest)
+
+		req = authorizeRequest(client.ClientID)
+		req.Scope = "admin"
+		_, err = svc.Authorize(ctx, req)
+		var oauthErr *service.OAuthError
+		require.ErrorAs(t, err, &oauthErr)
+		assert.Equal(t, service.OAuthInvalidScope, oauthErr.Code)
+		assert.Contains(t, oauthErr.RedirectURL(), "state=xyz")
+	})
+
+	t.Run("用户拒绝授权", func(t *testing.T) {
+		redirectTo, err := svc.Approve(ctx, 7, authorizeRequest(client.ClientID), false)
+		require.NoError(t, err)
+		assert.Contains(t, redirectTo, "error=access_denied")
+	})
+}
+
+func TestOAuthService_ClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	svc, tokens, repo := newTestOAuthService(t)
+	client := registerClient(t, svc, repo, service.RegisterClientDTO{
+		Name:         "billing",
+		GrantTypes:   []string{service.GrantClientCredentials},
+		Scopes:       []string{"orders:read"},
+		Confidential: true,
+	})
+	require.NotEmpty(t, client.Secret)
+
+	_, err := svc.Token(ctx, service.TokenRequest{
+		GrantType: service.GrantClientCredentials, ClientID: client.ClientID, ClientSecret: "wrong",
+	})
+	assertOAuthError(t, err, service.OAuthInvalidClient)
+
+	token, err := svc.Token(ctx, service.TokenRequest{
+		GrantType: service.GrantClientCredentials, ClientID: client.ClientID, ClientSecret: client.Secret,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, token.RefreshToken)
+	assert.Equal(t, "orders:read", token.Scope)
+
+	// 自省：active 且没有 sub；吊销后变为 inactive
+	info, err := svc.Introspect(ctx, client.ClientID, client.Secret, token.AccessToken, "")
+	require.NoError(t, err)
+	assert.True(t, info.Active)
+	assert.Equal(t, client.ClientID, info.ClientID)
+	assert.Empty(t, info.Subject)
+
+	// 第一方登录的 Token 不属于该客户端，不能通过自省探测
+	session, err := tokens.Issue(ctx, 7, auth.ScopeAll)
+	require.NoError(t, err)
+	for _, tok := range []string{session.AccessToken, session.RefreshToken} {
+		info, err := svc.Introspect(ctx, client.ClientID, client.Secret, tok, "")
+		require.NoError(t, err)
+		assert.False(t, info.Active)
+	}
+
+	require.NoError(t, svc.Revoke(ctx, client.ClientID, client.Secret, token.AccessToken, ""))
+	info, err = svc.Introspect(ctx, client.ClientID, client.Secret, token.AccessToken, "")
+	require.NoError(t, err)
+	assert.False(t, info.Active)
+
+	// 公开客户端不能注册 client_credentials
+	_, err = svc.RegisterClient(ctx, service.RegisterClientDTO{
+		Name: "spa", GrantTypes: []string{service.GrantClientCredentials},
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidClientMetadata)
+}
+
+func TestOAuthService_RegisterClient_RedirectURI(t *testing.T) {
+	tests := []struct {
+		uri   string
+		valid bool
+	}{
+		{"https://app.example.com/callback", true},
+		{"http://127.0.0.1:8080/callback", true},
+		{"http://[::1]/callback", true},
+		{"http://localhost/callback", true},
+		{"com.example.app:/oauth2redirect", true},
+		{"http://app.example.com/callback", false},
+		{"javascript:alert(1)", false},
+		{"data:text/html,hi", false},
+		{"myapp://callback", false},
+		{"https://app.example.com/callback#frag", false},
+		{"/callback", false},
+	}
+	for _, tt := range tests {
+		svc, _, repo := newTestOAuthService(t)
+		if tt.valid {
+			repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil)
+		}
+		_, err := svc.RegisterClient(context.Background(), service.RegisterClientDTO{Name: "app", RedirectURIs: []string{tt.uri}})
+		if tt.valid {
+			assert.NoError(t, err, tt.uri)
+		} else {
+			assert.ErrorIs(t, err, service.ErrInvalidClientMetadata, tt.uri)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/hash"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateTTL 从跳转到 IdP 到回调的最长时间
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrOIDCDisabled = errs.NotFound(40403, "oidc login is not enabled")
+	// ErrInvalidOIDCState state 不存在、已使用或已过期 (也可能是 CSRF)
+	ErrInvalidOIDCState = errs.Validation(42207, "invalid or expired oidc state").WithField("state")
+	// ErrOIDCLoginFailed 与 IdP 交换 code 或校验 ID Token 失败，具体原因只记录在日志中
+	ErrOIDCLoginFailed = errs.Unauthorized(40108, "oidc login failed")
+	// ErrOIDCRejected 用户在 IdP 取消了登录或 IdP 返回错误
+	ErrOIDCRejected         = errs.Unauthorized(40109, "sign in was cancelled or rejected by the identity provider")
+	ErrOIDCEmailNotVerified = errs.Forbidden(40302, "identity provider did not return a verified email")
+	ErrOIDCDomainNotAllowed = errs.Forbidden(40303, "email domain is not allowed to sign in")
+	// ErrOIDCUserNotFound 本地没有该邮箱的用户，且未开启自动创建
+	ErrOIDCUserNotFound = errs.Forbidden(40304, "no local account for this identity")
+)
+
+// oidcState 跳转 IdP 前保存的一次性参数
+type oidcState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"` // PKCE code_verifier
+}
+
+// oidcClaims ID Token 中用到的字段
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// OIDCService 通过外部 OIDC 身份提供方登录 (Relying Party)
+//
+// 流程: Redirect 生成 state/nonce/PKCE -> 用户在 IdP 登录 -> Callback 用 code 换 ID Token，
+// 校验签名、iss、aud、exp、nonce 后按 (issuer, sub) 或已验证邮箱找到本地用户，最后走正常登录签发 JWT
+//
+// Redis 结构:
+//
+//	oidc:state:<state>   String JSON{nonce, verifier}  TTL = 10m
+//
+// 发现文档在第一次使用时才拉取，IdP 暂时不可用不会影响服务启动
+type OIDCService struct {
+	config     *config.Config
+	users      domain.UserRepository
+	identities domain.UserIdentityRepository
+	login      *UserService
+	tokens     *TokenService
+	hasher     hash.Hasher
+	redis      *redis.Client
+	txm        domain.TxManager
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewOIDCService(
+	cfg *config.Config,
+	users domain.UserRepository,
+	identities domain.UserIdentityRepository,
+	login *UserService,
+	tokens *TokenService,
+	hasher hash.Hasher,
+	rdb *redis.Client,
+	txm domain.TxManager,
+) *OIDCService {
+	return &OIDCService{
+		config:     cfg,
+		users:      users,
+		identities: identities,
+		login:      login,
+		tokens:     tokens,
+		hasher:     hasher,
+		redis:      rdb,
+		txm:        txm,
+	}
+}
+
+// Enabled 是否开启了 OIDC 登录
+func (s *OIDCService) Enabled() bool {
+	return s.config.Auth.OIDC.Enabled
+}
+
+// AuthURL 生成跳转 IdP 的授权地址，返回的 state 需要同时写入浏览器 Cookie 以防登录 CSRF
+func (s *OIDCService) AuthURL(ctx context.Context) (string, string, error) {
+	oauthCfg, _, err := s.oauth2Config(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	data, err := json.Marshal(oidcState{Nonce: nonce, Verifier: verifier})
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.redis.Set(ctx, oidcStateKey(state), data, oidcStateTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("failed to store oidc state: %w", err)
+	}
+
+	return oauthCfg.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), state, nil
+}
+
+// Callback 处理 IdP 回调：code 换 ID Token 并完成本地登录
+func (s *OIDCService) Callback(ctx context.Context, state, code string) (*LoginResponse, error) {
+	oauthCfg, provider, err := s.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// GETDEL 保证 state 只能使用一次
+	data, err := s.redis.GetDel(ctx, oidcStateKey(state)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidOIDCState
+		}
+		return nil, fmt.Errorf("failed to load oidc state: %w", err)
+	}
+	var saved oidcState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, ErrInvalidOIDCState
+	}
+
+	token, err := oauthCfg.Exchange(ctx, code, oauth2.VerifierOption(saved.Verifier))
+	if err != nil {
+		return nil, ErrOIDCLoginFailed.Wrap(fmt.Errorf("exchange code: %w", err))
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrOIDCLoginFailed.Wrap(errors.New("token response has no id_token"))
+	}
+
+	// 校验签名 (IdP 的 JWKS)、iss、aud、exp
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: oauthCfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, ErrOIDCLoginFailed.Wrap(fmt.Errorf("verify id token: %w", err))
+	}
+	if idToken.Nonce != saved.Nonce {
+		return nil, ErrOIDCLoginFailed.Wrap(errors.New("nonce mismatch"))
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, ErrOIDCLoginFailed.Wrap(fmt.Errorf("decode claims: %w", err))
+	}
+
+	user, err := s.resolveUser(ctx, idToken.Issuer, idToken.Subject, claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.login.LoginUser(ctx, user)
+}
+
+// resolveUser 按 (issuer, sub) 找到已绑定的用户；首次登录时按已验证邮箱绑定或创建
+func (s *OIDCService) resolveUser(ctx context.Context, issuer, subject string, claims oidcClaims) (*domain.User, error) {
+	identity, err := s.identities.FindBySubject(ctx, issuer, subject)
+	if err == nil {
+		user, err := s.users.FindByID(ctx, identity.UserID)
+		// 绑定的账户已注销 (软删除)，不能通过外部登录绕过注销
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, ErrOIDCUserNotFound
+		}
+		return user, err
+	}
+	if !errors.Is(err, errs.ErrNotFound) {
+		return nil, err
+	}
+
+	// 只有 IdP 确认过的邮箱才能用来关联本地账户，否则任何人都能冒用别人的邮箱
+	email := normalizeEmail(claims.Email)
+	if email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+	if !s.domainAllowed(email) {
+		return nil, ErrOIDCDomainNotAllowed
+	}
+
+	// 创建/接管用户与写入绑定在同一事务中，避免留下没有绑定的用户
+	var (
+		user    *domain.User
+		claimed bool
+	)
+	err = s.txm.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if user, claimed, err = s.findOrCreateUser(ctx, email, claims.Name); err != nil {
+			return err
+		}
+		if err := s.identities.Create(ctx, &domain.UserIdentity{
+			UserID:   user.ID,
+			Provider: issuer,
+			Subject:  subject,
+			Email:    email,
+		}); err != nil {
+			return fmt.Errorf("failed to link identity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再清理缓存和旧 Token
+	if claimed {
+		s.redis.Del(ctx, profileCacheKey(user.ID))
+		// 抢注者可能已经创建了 API Key，与会话一起作废
+		if err := s.login.RevokeCredentials(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// findOrCreateUser 按邮箱找到本地用户 (必要时接管未验证的账户)，不存在时自动创建；claimed 表示接管了未验证的账户
+func (s *OIDCService) findOrCreateUser(ctx context.Context, email, name string) (*domain.User, bool, error) {
+	user, err := s.users.FindByEmail(ctx, email)
+	switch {
+	case err == nil:
+		claimed, err := s.claimUnverified(ctx, user)
+		return user, claimed, err
+	case errors.Is(err, errs.ErrNotFound):
+		if !s.config.Auth.OIDC.AutoCreate {
+			return nil, false, ErrOIDCUserNotFound
+		}
+		user, err := s.createUser(ctx, email, name)
+		return user, false, err
+	default:
+		return nil, false, err
+	}
+}
+
+// claimUnverified 本地账户邮箱尚未验证时，无法证明注册者就是邮箱主人 (可能是抢注)
+// IdP 已证明了邮箱归属，因此标记为已验证并作废原密码；已签发的 Token 与 API Key 由调用方在提交后吊销
+func (s *OIDCService) claimUnverified(ctx context.Context, user *domain.User) (bool, error) {
+	if user.IsVerified() {
+		return false, nil
+	}
+
+	password, err := s.unusablePassword()
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	user.Password = password
+	if err := s.users.Update(ctx, user); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// createUser 自动创建本地用户，邮箱由 IdP 验证过，密码随机 (之后可通过忘记密码设置)
+func (s *OIDCService) createUser(ctx context.Context, email, name string) (*domain.User, error) {
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	password, err := s.unusablePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		Name:            name,
+		Email:           email,
+		Password:        password,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// unusablePassword 没人知道明文的随机密码哈希
+func (s *OIDCService) unusablePassword() (string, error) {
+	plain, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	return s.hasher.Hash(plain)
+}
+
+func (s *OIDCService) domainAllowed(email string) bool {
+	allowed := s.config.Auth.OIDC.AllowedDomains
+	if len(allowed) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return slices.ContainsFunc(allowed, func(d string) bool { return strings.EqualFold(d, domain) })
+}
+
+// oauth2Config 懒加载发现文档，失败时下次请求重试
+func (s *OIDCService) oauth2Config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	cfg := s.config.Auth.OIDC
+	if !cfg.Enabled {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider == nil {
+		provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("oidc discovery: %w", err)
+		}
+		s.provider = provider
+	}
+
+	return &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Endpoint:     s.provider.Endpoint(),
+		Scopes:       cfg.Scopes,
+	}, s.provider, nil
+}
+
+func oidcStateKey(state string) string {
+	return "oidc:state:" + state
+}
@@ -0,0 +1,191 @@
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/domain/mocks"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/errs"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// stubIdP 本地 OIDC 身份提供方：发现文档 + JWKS + Token 端点
+type stubIdP struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims // 下一次 Token 端点签发的 ID Token 载荷 (iss/aud/exp 自动补齐)
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &stubIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "stub", "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "good-code" || r.PostFormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := jwt.MapClaims{"iss": idp.URL, "aud": "go-artisan", "exp": time.Now().Add(time.Minute).Unix(), "iat": time.Now().Unix()}
+		for k, v := range idp.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "stub"
+		idToken, err := token.SignedString(key)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at", "token_type": "Bearer", "id_token": idToken})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+func newTestOIDCService(t *testing.T, idp *stubIdP) (*service.OIDCService, *mocks.MockUserRepository, *mocks.MockUserIdentityRepository) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	identities := mocks.NewMockUserIdentityRepository(ctrl)
+	txm := mocks.NewMockTxManager(ctrl)
+	txm.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	}).AnyTimes()
+
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret:     "test-secret",
+			TTL:        15 * time.Minute,
+			RefreshTTL: 24 * time.Hour,
+			Issuer:     "go-artisan",
+			OIDC: config.OIDCConfig{
+				Enabled:     true,
+				Issuer:      idp.URL,
+				ClientID:    "go-artisan",
+				RedirectURL: "http://localhost:8080/api/auth/oidc/callback",
+				Scopes:      []string{"openid", "email"},
+				AutoCreate:  true,
+			},
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(cfg, rdb)
+	require.NoError(t, err)
+	login := service.NewUserService(users, nil, cfg, rdb, tokens, service.NewLoginThrottle(cfg, rdb), nil, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
+	return service.NewOIDCService(cfg, users, identities, login, tokens, newHasher(t), rdb, txm), users, identities
+}
+
+// startLogin 走一遍跳转，返回 state 与 IdP 会写进 ID Token 的 nonce
+func startLogin(t *testing.T, svc *service.OIDCService) (string, string) {
+	authURL, state, err := svc.AuthURL(context.Background())
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, state, u.Query().Get("state"))
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+	return state, u.Query().Get("nonce")
+}
+
+func TestOIDCService_Callback(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("首次登录：按已验证邮箱创建用户并绑定身份，签发本地 JWT", func(t *testing.T) {
+		idp := newStubIdP(t)
+		svc, users, identities := newTestOIDCService(t, idp)
+		state, nonce := startLogin(t, svc)
+		idp.claims = jwt.MapClaims{"sub": "u-1", "nonce": nonce, "email": "Alice@Example.com", "email_verified": true, "name": "Alice"}
+
+		identities.EXPECT().FindBySubject(gomock.Any(), idp.URL, "u-1").Return(nil, errs.ErrNotFound)
+		users.EXPECT().FindByEmail(gomock.Any(), "alice@example.com").Return(nil, errs.ErrNotFound)
+		users.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *domain.User) error {
+			assert.True(t, u.IsVerified())
+			u.ID = 9
+			return nil
+		})
+		identities.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, i *domain.UserIdentity) error {
+			assert.Equal(t, uint(9), i.UserID)
+			return nil
+		})
+
+		res, err := svc.Callback(ctx, state, "good-code")
+		require.NoError(t, err)
+		assert.NotEmpty(t, res.Token)
+		assert.Equal(t, "Alice", res.User.Name)
+
+		// state 只能使用一次
+		_, err = svc.Callback(ctx, state, "good-code")
+		assert.ErrorIs(t, err, service.ErrInvalidOIDCState)
+	})
+
+	t.Run("已绑定：按 (issuer, sub) 找到用户", func(t *testing.T) {
+		idp := newStubIdP(t)
+		svc, users, identities := newTestOIDCService(t, idp)
+		state, nonce := startLogin(t, svc)
+		idp.claims = jwt.MapClaims{"sub": "u-1", "nonce": nonce}
+
+		identities.EXPECT().FindBySubject(gomock.Any(), idp.URL, "u-1").Return(&domain.UserIdentity{UserID: 3}, nil)
+		users.EXPECT().FindByID(gomock.Any(), uint(3)).Return(&domain.User{ID: 3}, nil)
+
+		res, err := svc.Callback(ctx, state, "good-code")
+		require.NoError(t, err)
+		assert.Equal(t, uint(3), res.User.ID)
+	})
+
+	t.Run("nonce 不匹配拒绝登录", func(t *testing.T) {
+		idp := newStubIdP(t)
+		svc, _, _ := newTestOIDCService(t, idp)
+		state, _ := startLogin(t, svc)
+		idp.claims = jwt.MapClaims{"sub": "u-1", "nonce": "replayed"}
+
+		_, err := svc.Callback(ctx, state, "good-code")
+		assert.ErrorIs(t, err, service.ErrOIDCLoginFailed)
+	})
+
+	t.Run("未验证的邮箱不能关联本地账户", func(t *testing.T) {
+		idp := newStubIdP(t)
+		svc, _, identities := newTestOIDCService(t, idp)
+		state, nonce := startLogin(t, svc)
+		idp.claims = jwt.MapClaims{"sub": "u-2", "nonce": nonce, "email": "bob@example.com", "email_verified": false}
+
+		identities.EXPECT().FindBySubject(gomock.Any(), idp.URL, "u-2").Return(nil, errs.ErrNotFound)
+
+		_, err := svc.Callback(ctx, state, "good-code")
+		assert.ErrorIs(t, err, service.ErrOIDCEmailNotVerified)
+	})
+}
@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/hash"
+	"go-artisan/pkg/mail"
+	"go-artisan/pkg/validator"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// forgotTimeout 后台生成 Token 并发送邮件的时限
+const forgotTimeout = 30 * time.Second
+
+var (
+	// ErrInvalidResetToken 重置 Token 不存在、已过期或已被使用 (不区分原因，避免泄露信息)
+	ErrInvalidResetToken = errs.Validation(42205, "invalid or expired password reset token").WithField("token")
+	// ErrPasswordResetThrottled 同一邮箱或 IP 申请重置过于频繁 (邮箱是否存在都一样计数)
+	ErrPasswordResetThrottled = errs.RateLimited(42904, "password reset already requested, please try again later")
+)
+
+// ForgotPasswordDTO 输入对象
+type ForgotPasswordDTO struct {
+	Email string
+	IP    string
+}
+
+// ResetPasswordDTO 输入对象
+type ResetPasswordDTO struct {
+	Token    string
+	Password string
+}
+
+// PasswordResetService 找回密码
+//
+// 流程：Forgot 生成随机 Token，库里只存 SHA-256，明文通过邮件发给用户；
+// Reset 校验 Token 后原子地标记为已使用，更新密码并吊销该用户所有已签发的 JWT 与 API Key
+//
+// Redis 结构:
+//
+//	password:forgot:email:<sha256>  申请冷却  TTL = password_reset_cooldown
+//	password:forgot:ip:<ip>         申请冷却  TTL = password_reset_cooldown
+type PasswordResetService struct {
+	users   domain.UserRepository
+	resets  domain.PasswordResetRepository
+	apiKeys domain.APIKeyRepository
+	tokens  *TokenService
+	mailer  mail.Mailer
+	hasher  hash.Hasher
+	policy  *validator.PasswordPolicy
+	txm     domain.TxManager
+	config  *config.Config
+	redis   *redis.Client
+	logger  *slog.Logger
+
+	pending sync.WaitGroup // 尚未完成的后台发送
+}
+
+func NewPasswordResetService(
+	users domain.UserRepository,
+	resets domain.PasswordResetRepository,
+	apiKeys domain.APIKeyRepository,
+	tokens *TokenService,
+	mailer mail.Mailer,
+	hasher hash.Hasher,
+	policy *validator.PasswordPolicy,
+	txm domain.TxManager,
+	cfg *config.Config,
+	rdb *redis.Client,
+	logger *slog.Logger,
+) *PasswordResetService {
+	return &PasswordResetService{
+		users:   users,
+		resets:  resets,
+		apiKeys: apiKeys,
+		tokens:  tokens,
+		mailer:  mailer,
+		hasher:  hasher,
+		policy:  policy,
+		txm:     txm,
+		config:  cfg,
+		redis:   rdb,
+		logger:  logger,
+	}
+}
+
+// Forgot 发送重置密码邮件
+// 邮箱不存在时同样返回 nil；查库、生成 Token 和发邮件都在后台完成，响应时间也无法区分邮箱是否已注册
+func (s *PasswordResetService) Forgot(ctx context.Context, req ForgotPasswordDTO) error {
+	if err := s.cooldown(ctx, req); err != nil {
+		return err
+	}
+
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		// 请求结束后 ctx 会被取消，后台任务不能继承
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), forgotTimeout)
+		defer cancel()
+		if err := s.sendResetLink(ctx, req.Email); err != nil {
+			s.logger.Error("Failed to send password reset email", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Wait 等待后台发送全部完成，用于优雅退出
+func (s *PasswordResetService) Wait() {
+	s.pending.Wait()
+}
+
+// cooldown 同一 IP、同一邮箱在冷却期内只能申请一次，防止借本接口向他人邮箱轰炸
+func (s *PasswordResetService) cooldown(ctx context.Context, req ForgotPasswordDTO) error {
+	cooldown := s.config.Auth.PasswordResetCooldown
+	if cooldown <= 0 {
+		return nil
+	}
+	for _, key := range []string{"password:forgot:ip:" + req.IP, "password:forgot:email:" + emailHash(req.Email)} {
+		ok, err := s.redis.SetNX(ctx, key, 1, cooldown).Result()
+		if err != nil {
+			return fmt.Errorf("failed to throttle password reset: %w", err)
+		}
+		if !ok {
+			ttl, _ := s.redis.PTTL(ctx, key).Result()
+			return ErrPasswordResetThrottled.RetryIn(ttl)
+		}
+	}
+	return nil
+}
+
+// sendResetLink 为已注册的邮箱生成新 Token 并发送邮件，同一用户只保留最新的一个 Token
+func (s *PasswordResetService) sendResetLink(ctx context.Context, email string) error {
+	user, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.resets.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to delete old reset tokens: %w", err)
+	}
+
+	plain, err := randomToken()
+	if err != nil {
+		return err
+	}
+	ttl := s.config.Auth.PasswordResetTTL
+	if err := s.resets.Create(ctx, &domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	link := s.config.App.URL + "/password/reset?token=" + url.QueryEscape(plain)
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. "+
+			"Open the link below within %s to choose a new one:\n\n%s\n\n"+
+			"If you did not request this, you can ignore this email.\n", user.Name, ttl, link),
+	})
+}
+
+// Reset 使用邮件中的 Token 设置新密码
+func (s *PasswordResetService) Reset(ctx context.Context, req ResetPasswordDTO) error {
+	token, err := s.resets.FindByHash(ctx, hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.users.FindByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	// 新密码不能包含该用户的邮箱或姓名
+	if err := s.policy.Check(req.Password, user.Email, user.Name); err != nil {
+		return passwordPolicyError(err)
+	}
+
+	// 先哈希再抢占 Token，避免标记成功后哈希失败导致 Token 白白作废
+	hashed, err := s.hasher.Hash(req.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	// 标记与改密码在同一事务中：改密码失败时 Token 不会白白作废
+	err = s.txm.WithinTransaction(ctx, func(ctx context.Context) error {
+		// 并发请求只有一个能把 used_at 从 NULL 改掉
+		ok, err := s.resets.MarkUsed(ctx, token.ID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidResetToken
+		}
+
+		user.Password = hashed
+		if err := s.users.Update(ctx, user); err != nil {
+			return err
+		}
+		// API Key 不会随密码失效，攻击者可能借它保持访问
+		return s.apiKeys.DeleteByUserID(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 旧密码签发的 Token 全部失效，防止攻击者保持已窃取的会话
+	if err := s.tokens.RevokeAll(ctx, user.ID); err != nil {
+		return err
+	}
+	s.redis.Del(ctx, profileCacheKey(user.ID))
+	return nil
+}
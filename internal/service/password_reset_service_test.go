@@ -0,0 +1,177 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/domain/mocks"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/mail"
+	"go-artisan/pkg/validator"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// captureMailer 记录发出的邮件，代替真实发送
+type captureMailer struct {
+	sent []mail.Message
+}
+
+func (m *captureMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// resetTokenFrom 从邮件正文的链接里取出明文 Token
+func resetTokenFrom(t *testing.T, body string) string {
+	i := strings.Index(body, "http://")
+	require.NotEqual(t, -1, i, "reset link not found in mail body")
+	link, err := url.Parse(strings.Fields(body[i:])[0])
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func newPolicy(t *testing.T) *validator.PasswordPolicy {
+	policy, err := validator.NewPasswordPolicy(validator.PasswordPolicyConfig{MinLength: 8, DisallowUserInfo: true})
+	require.NoError(t, err)
+	return policy
+}
+
+func TestPasswordResetService_ForgotAndReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	resets := mocks.NewMockPasswordResetRepository(ctrl)
+	apiKeys := mocks.NewMockAPIKeyRepository(ctrl)
+	mailer := &captureMailer{}
+
+	cfg := &config.Config{
+		App: config.AppConfig{URL: "http://localhost:8080"},
+		Auth: config.AuthConfig{
+			Secret:           "test-secret",
+			TTL:              time.Hour,
+			RefreshTTL:       24 * time.Hour,
+			PasswordResetTTL: time.Hour,
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(cfg, rdb)
+	require.NoError(t, err)
+	txm := mocks.NewMockTxManager(ctrl)
+	txm.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+		return fn(ctx)
+	})
+	svc := service.NewPasswordResetService(users, resets, apiKeys, tokens, mailer, newHasher(t), newPolicy(t), txm, cfg, rdb, slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	user := &domain.User{ID: 1, Name: "Dan", Email: "dan@example.com", Password: hashPassword(t, "old-password")}
+	pair, err := tokens.Issue(ctx, user.ID)
+	require.NoError(t, err)
+
+	// 1. 申请重置：库里只存哈希，邮件里是明文
+	var stored *domain.PasswordResetToken
+	users.EXPECT().FindByEmail(gomock.Any(), user.Email).Return(user, nil)
+	resets.EXPECT().DeleteByUserID(gomock.Any(), user.ID).Return(nil)
+	resets.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, tok *domain.PasswordResetToken) error {
+		stored = tok
+		stored.ID = 10
+		return nil
+	})
+	require.NoError(t, svc.Forgot(ctx, service.ForgotPasswordDTO{Email: user.Email, IP: "10.0.0.1"}))
+	svc.Wait()
+	require.Len(t, mailer.sent, 1)
+	plain := resetTokenFrom(t, mailer.sent[0].Body)
+	assert.NotEmpty(t, plain)
+	assert.NotEqual(t, plain, stored.TokenHash)
+
+	// 2. 重置成功：密码更新，旧 Token 与 API Key 全部失效
+	resets.EXPECT().FindByHash(gomock.Any(), stored.TokenHash).Return(stored, nil)
+	users.EXPECT().FindByID(gomock.Any(), user.ID).Return(user, nil)
+	resets.EXPECT().MarkUsed(gomock.Any(), stored.ID).Return(true, nil)
+	users.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *domain.User) error {
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(u.Password), []byte("new-password")))
+		return nil
+	})
+	apiKeys.EXPECT().DeleteByUserID(gomock.Any(), user.ID).Return(nil)
+	require.NoError(t, svc.Reset(ctx, service.ResetPasswordDTO{Token: plain, Password: "new-password"}))
+
+	_, err = tokens.Authenticate(ctx, pair.AccessToken)
+	assert.ErrorIs(t, err, service.ErrTokenRevoked)
+
+	// 3. 同一个 Token 不能再次使用
+	now := time.Now()
+	stored.UsedAt = &now
+	resets.EXPECT().FindByHash(gomock.Any(), stored.TokenHash).Return(stored, nil)
+	err = svc.Reset(ctx, service.ResetPasswordDTO{Token: plain, Password: "another-password"})
+	assert.ErrorIs(t, err, service.ErrInvalidResetToken)
+}
+
+func TestPasswordResetService_Forgot_UnknownEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	resets := mocks.NewMockPasswordResetRepository(ctrl)
+	mailer := &captureMailer{}
+	cfg := &config.Config{Auth: config.AuthConfig{PasswordResetTTL: time.Hour}}
+
+	svc := service.NewPasswordResetService(users, resets, nil, nil, mailer, newHasher(t), newPolicy(t), nil, cfg, nil, slog.New(slog.DiscardHandler))
+
+	users.EXPECT().FindByEmail(gomock.Any(), "nobody@example.com").Return(nil, errs.ErrNotFound)
+
+	// 不存在的邮箱静默成功，也不发邮件
+	assert.NoError(t, svc.Forgot(context.Background(), service.ForgotPasswordDTO{Email: "nobody@example.com", IP: "10.0.0.1"}))
+	svc.Wait()
+	assert.Empty(t, mailer.sent)
+}
+
+func TestPasswordResetService_Forgot_Cooldown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	cfg := &config.Config{Auth: config.AuthConfig{PasswordResetTTL: time.Hour, PasswordResetCooldown: time.Minute}}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	svc := service.NewPasswordResetService(users, nil, nil, nil, &captureMailer{}, newHasher(t), newPolicy(t), nil, cfg, rdb, slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	users.EXPECT().FindByEmail(gomock.Any(), gomock.Any()).Return(nil, errs.ErrNotFound).Times(2)
+
+	require.NoError(t, svc.Forgot(ctx, service.ForgotPasswordDTO{Email: "victim@example.com", IP: "10.0.0.1"}))
+	// 换 IP 也不能反复向同一邮箱发信，大小写不同视为同一邮箱
+	err := svc.Forgot(ctx, service.ForgotPasswordDTO{Email: "Victim@example.com", IP: "10.0.0.2"})
+	assert.ErrorIs(t, err, service.ErrPasswordResetThrottled)
+	// 同一 IP 也不能换邮箱连续申请
+	err = svc.Forgot(ctx, service.ForgotPasswordDTO{Email: "other@example.com", IP: "10.0.0.1"})
+	assert.ErrorIs(t, err, service.ErrPasswordResetThrottled)
+
+	var e *errs.Error
+	require.ErrorAs(t, err, &e)
+	assert.Positive(t, e.RetryAfter)
+
+	require.NoError(t, svc.Forgot(ctx, service.ForgotPasswordDTO{Email: "other@example.com", IP: "10.0.0.3"}))
+	svc.Wait()
+}
+
+func TestPasswordResetService_Reset_Expired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	resets := mocks.NewMockPasswordResetRepository(ctrl)
+	cfg := &config.Config{Auth: config.AuthConfig{PasswordResetTTL: time.Hour}}
+
+	svc := service.NewPasswordResetService(users, resets, nil, nil, &captureMailer{}, newHasher(t), newPolicy(t), nil, cfg, nil, slog.New(slog.DiscardHandler))
+
+	resets.EXPECT().FindByHash(gomock.Any(), gomock.Any()).Return(&domain.PasswordResetToken{
+		ID: 1, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute),
+	}, nil)
+
+	err := svc.Reset(context.Background(), service.ResetPasswordDTO{Token: "expired", Password: "new-password"})
+	assert.ErrorIs(t, err, service.ErrInvalidResetToken)
+}
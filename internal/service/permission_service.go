@@ -0,0 +1,300 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-artisan/pkg/errs"
+
+	"github.com/casbin/casbin/v2"
+)
+
+const (
+	userSubjectPrefix = "user:"
+	roleSubjectPrefix = "role:"
+	adminRole         = roleSubjectPrefix + "admin"
+)
+
+var ErrInvalidSubject = errs.Validation(42206, `subject must look like "user:<id>" or "role:<name>"`).WithField("subject")
+
+// Policy 一条 p 规则：sub 可以对 obj 执行 act
+type Policy struct {
+	Subject string `json:"sub" binding:"required"`
+	Object  string `json:"obj" binding:"required"` // 支持 keyMatch2 路径，如 /api/orders/:id
+	Action  string `json:"act" binding:"required"` // HTTP 方法，* 代表全部
+}
+
+// RoleAssignment 一条 g 规则：subject 拥有 role
+type RoleAssignment struct {
+	Subject string `json:"sub" binding:"required"`  // user:1
+	Role    string `json:"role" binding:"required"` // admin 或 role:admin
+}
+
+// PermissionService 封装 Casbin 策略管理，HTTP 管理接口与 artisan CLI 共用
+type PermissionService struct {
+	enforcer *casbin.SyncedEnforcer
+}
+
+func NewPermissionService(e *casbin.SyncedEnforcer) *PermissionService {
+	return &PermissionService{enforcer: e}
+}
+
+// UserSubject 用户在 Casbin 中的 subject，如 user:1
+func UserSubject(id uint) string {
+	return fmt.Sprintf("%s%d", userSubjectPrefix, id)
+}
+
+// RoleSubject 角色在 Casbin 中的 subject，admin 与 role:admin 等价
+func RoleSubject(name string) string {
+	return roleSubjectPrefix + strings.TrimPrefix(name, roleSubjectPrefix)
+}
+
+// Enforce 判断 subject 能否对 obj 执行 act
+func (s *PermissionService) Enforce(sub, obj, act string) (bool, error) {
+	return s.enforcer.Enforce(sub, obj, act)
+}
+
+// PermissionsFor 列出 subject 实际拥有的权限 (包含通过角色继承的)
+func (s *PermissionService) PermissionsFor(sub string) ([]Policy, error) {
+	rules, err := s.enforcer.GetImplicitPermissionsForUser(sub)
+	if err != nil {
+		return nil, err
+	}
+	return toPolicies(rules), nil
+}
+
+// RolesFor 列出 subject 拥有的全部角色 (包含间接角色)
+func (s *PermissionService) RolesFor(sub string) ([]string, error) {
+	return s.enforcer.GetImplicitRolesForUser(sub)
+}
+
+// Policies 列出全部 p 规则
+func (s *PermissionService) Policies() ([]Policy, error) {
+	rules, err := s.enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return toPolicies(rules), nil
+}
+
+// AddPolicy 新增 p 规则，已存在时返回 false
+func (s *PermissionService) AddPolicy(p Policy) (bool, error) {
+	if err := validateSubject(p.Subject); err != nil {
+		return false, err
+	}
+	return s.enforcer.AddPolicy(p.Subject, p.Object, strings.ToUpper(p.Action))
+}
+
+// RemovePolicy 删除 p 规则，不存在时返回 false
+func (s *PermissionService) RemovePolicy(p Policy) (bool, error) {
+	return s.enforcer.RemovePolicy(p.Subject, p.Object, strings.ToUpper(p.Action))
+}
+
+// RoleAssignments 列出全部 g 规则
+func (s *PermissionService) RoleAssignments() ([]RoleAssignment, error) {
+	rules, err := s.enforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, err
+	}
+	assignments := make([]RoleAssignment, 0, len(rules))
+	for _, r := range rules {
+		if len(r) < 2 {
+			continue
+		}
+		assignments = append(assignments, RoleAssignment{Subject: r[0], Role: r[1]})
+	}
+	return assignments, nil
+}
+
+// AssignRole 给 subject 分配角色，已存在时返回 false
+func (s *PermissionService) AssignRole(a RoleAssignment) (bool, error) {
+	if err := validateSubject(a.Subject); err != nil {
+		return false, err
+	}
+	return s.enforcer.AddGroupingPolicy(a.Subject, RoleSubject(a.Role))
+}
+
+// UnassignRole 收回 subject 的角色，不存在时返回 false；不能收回最后一个管理员
+func (s *PermissionService) UnassignRole(a RoleAssignment) (bool, error) {
+	role := RoleSubject(a.Role)
+	if role == adminRole {
+		if err := s.checkLastAdmin(a.Subject); err != nil {
+			return false, err
+		}
+	}
+	return s.enforcer.RemoveGroupingPolicy(a.Subject, role)
+}
+
+// RemoveSubject 删除 subject 的全部角色分配和直接授予的权限，没有任何规则时返回 false；不能删除最后一个管理员
+func (s *PermissionService) RemoveSubject(sub string) (bool, error) {
+	if err := s.checkLastAdmin(sub); err != nil {
+		return false, err
+	}
+	return s.enforcer.DeleteUser(sub)
+}
+
+// checkLastAdmin 收回 sub 的管理员角色后是否还有其他管理员，sub 本身不是管理员时直接通过
+func (s *PermissionService) checkLastAdmin(sub string) error {
+	admins, err := s.enforcer.GetFilteredGroupingPolicy(1, adminRole)
+	if err != nil {
+		return err
+	}
+	for _, g := range admins {
+		if g[0] != sub {
+			return nil
+		}
+	}
+	if len(admins) > 0 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+// Snapshot 当前 casbin_rule 中的全部策略
+func (s *PermissionService) Snapshot() (*PolicySet, error) {
+	policies, err := s.Policies()
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.RoleAssignments()
+	if err != nil {
+		return nil, err
+	}
+	set := &PolicySet{Policies: policies, Roles: roles}
+	set.sort()
+	return set, nil
+}
+
+// DiffOptions 比对时是否删除文件中没有的规则，默认只增不删
+type DiffOptions struct {
+	Prune      bool // 删除文件中没有的 p 规则
+	PruneRoles bool // 删除文件中没有的 g 规则；角色分配通常在运行时通过管理接口维护，需要单独开启
+}
+
+// ErrLastAdmin 变更会移除最后一个管理员角色分配，之后管理接口将拒绝所有人
+var ErrLastAdmin = errs.Conflict(40906, "refusing to remove the last role:admin assignment")
+
+// Diff 计算把当前策略变成 desired 需要的变更
+func (s *PermissionService) Diff(desired *PolicySet, opts DiffOptions) (*PolicyDiff, error) {
+	current, err := s.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &PolicyDiff{}
+	have := make(map[Policy]bool, len(current.Policies))
+	for _, p := range current.Policies {
+		have[p] = true
+	}
+	want := make(map[Policy]bool, len(desired.Policies))
+	for _, p := range desired.Policies {
+		if !have[p] && !want[p] {
+			diff.AddPolicies = append(diff.AddPolicies, p)
+		}
+		want[p] = true
+	}
+
+	haveRole := make(map[RoleAssignment]bool, len(current.Roles))
+	for _, g := range current.Roles {
+		haveRole[g] = true
+	}
+	wantRole := make(map[RoleAssignment]bool, len(desired.Roles))
+	for _, g := range desired.Roles {
+		g.Role = RoleSubject(g.Role) // admin 与 role:admin 等价
+		if !haveRole[g] && !wantRole[g] {
+			diff.AddRoles = append(diff.AddRoles, g)
+		}
+		wantRole[g] = true
+	}
+
+	if opts.Prune {
+		for _, p := range current.Policies {
+			if !want[p] {
+				diff.RemovePolicies = append(diff.RemovePolicies, p)
+			}
+		}
+	}
+	if opts.PruneRoles {
+		admins := 0
+		for _, g := range current.Roles {
+			if !wantRole[g] {
+				diff.RemoveRoles = append(diff.RemoveRoles, g)
+			} else if RoleSubject(g.Role) == adminRole {
+				admins++
+			}
+		}
+		for _, g := range diff.AddRoles {
+			if RoleSubject(g.Role) == adminRole {
+				admins++
+			}
+		}
+		if admins == 0 && slices.ContainsFunc(diff.RemoveRoles, func(g RoleAssignment) bool { return RoleSubject(g.Role) == adminRole }) {
+			return nil, ErrLastAdmin
+		}
+	}
+	return diff, nil
+}
+
+// Apply 批量执行变更 (gorm adapter 对每一批使用事务)
+func (s *PermissionService) Apply(diff *PolicyDiff) error {
+	if len(diff.RemovePolicies) > 0 {
+		if _, err := s.enforcer.RemovePolicies(policyRules(diff.RemovePolicies)); err != nil {
+			return fmt.Errorf("remove policies: %w", err)
+		}
+	}
+	if len(diff.RemoveRoles) > 0 {
+		if _, err := s.enforcer.RemoveGroupingPolicies(roleRules(diff.RemoveRoles)); err != nil {
+			return fmt.Errorf("remove role assignments: %w", err)
+		}
+	}
+	if len(diff.AddPolicies) > 0 {
+		if _, err := s.enforcer.AddPolicies(policyRules(diff.AddPolicies)); err != nil {
+			return fmt.Errorf("add policies: %w", err)
+		}
+	}
+	if len(diff.AddRoles) > 0 {
+		if _, err := s.enforcer.AddGroupingPolicies(roleRules(diff.AddRoles)); err != nil {
+			return fmt.Errorf("add role assignments: %w", err)
+		}
+	}
+	return nil
+}
+
+func policyRules(policies []Policy) [][]string {
+	rules := make([][]string, 0, len(policies))
+	for _, p := range policies {
+		rules = append(rules, []string{p.Subject, p.Object, p.Action})
+	}
+	return rules
+}
+
+func roleRules(roles []RoleAssignment) [][]string {
+	rules := make([][]string, 0, len(roles))
+	for _, g := range roles {
+		rules = append(rules, []string{g.Subject, RoleSubject(g.Role)})
+	}
+	return rules
+}
+
+func toPolicies(rules [][]string) []Policy {
+	policies := make([]Policy, 0, len(rules))
+	for _, r := range rules {
+		if len(r) < 3 {
+			continue
+		}
+		policies = append(policies, Policy{Subject: r[0], Object: r[1], Action: r[2]})
+	}
+	return policies
+}
+
+func validateSubject(sub string) error {
+	switch {
+	case strings.HasPrefix(sub, userSubjectPrefix) && len(sub) > len(userSubjectPrefix):
+		return nil
+	case strings.HasPrefix(sub, roleSubjectPrefix) && len(sub) > len(roleSubjectPrefix):
+		return nil
+	default:
+		return ErrInvalidSubject
+	}
+}
@@ -0,0 +1,163 @@
+package service_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go-artisan/internal/provider"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/errs"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionService_Enforce(t *testing.T) {
+	// 不接数据库，只验证模型与匹配逻辑
+	e, err := casbin.NewSyncedEnforcer(provider.NewCasbinModel())
+	require.NoError(t, err)
+	_, err = e.AddPolicy(provider.AdminPolicy)
+	require.NoError(t, err)
+
+	svc := service.NewPermissionService(e)
+
+	_, err = svc.AddPolicy(service.Policy{Subject: "role:sales", Object: "/api/orders/:id", Action: "get"})
+	require.NoError(t, err)
+	_, err = svc.AssignRole(service.RoleAssignment{Subject: service.UserSubject(1), Role: "sales"})
+	require.NoError(t, err)
+	_, err = svc.AssignRole(service.RoleAssignment{Subject: service.UserSubject(2), Role: "role:admin"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		sub  string
+		obj  string
+		act  string
+		want bool
+	}{
+		{"路径参数匹配", "user:1", "/api/orders/42", "GET", true},
+		{"方法不匹配", "user:1", "/api/orders/42", "DELETE", false},
+		{"多一级路径不匹配", "user:1", "/api/orders/42/items", "GET", false},
+		{"无角色用户", "user:3", "/api/orders/42", "GET", false},
+		{"管理员通配方法", "user:2", "/api/admin/policies", "DELETE", true},
+		{"非管理员访问管理接口", "user:1", "/api/admin/policies", "GET", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := svc.Enforce(tt.sub, tt.obj, tt.act)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ok)
+		})
+	}
+
+	_, err = svc.AddPolicy(service.Policy{Subject: "alice", Object: "/api/orders", Action: "GET"})
+	assert.ErrorIs(t, err, service.ErrInvalidSubject)
+}
+
+func TestPermissionService_DiffAndApply(t *testing.T) {
+	e, err := casbin.NewSyncedEnforcer(provider.NewCasbinModel())
+	require.NoError(t, err)
+	svc := service.NewPermissionService(e)
+
+	// 当前库里：一条会保留，一条文件里没有
+	_, err = svc.AddPolicy(service.Policy{Subject: "role:sales", Object: "/api/orders", Action: "GET"})
+	require.NoError(t, err)
+	_, err = svc.AddPolicy(service.Policy{Subject: "role:legacy", Object: "/api/old", Action: "GET"})
+	require.NoError(t, err)
+
+	desired, err := service.ReadPolicyCSV(strings.NewReader(`
+# comment
+p, role:sales, /api/orders, get
+p, role:sales, /api/orders/:id, GET
+g, user:1, sales
+`))
+	require.NoError(t, err)
+
+	diff, err := svc.Diff(desired, service.DiffOptions{})
+	require.NoError(t, err)
+	assert.Len(t, diff.AddPolicies, 1)
+	assert.Len(t, diff.AddRoles, 1)
+	assert.Empty(t, diff.RemovePolicies, "不带 --prune 时不删除")
+
+	diff, err = svc.Diff(desired, service.DiffOptions{Prune: true, PruneRoles: true})
+	require.NoError(t, err)
+	require.Len(t, diff.RemovePolicies, 1)
+	assert.Equal(t, "role:legacy", diff.RemovePolicies[0].Subject)
+
+	require.NoError(t, svc.Apply(diff))
+	diff, err = svc.Diff(desired, service.DiffOptions{Prune: true, PruneRoles: true})
+	require.NoError(t, err)
+	assert.True(t, diff.Empty(), "应用后再次比对应当没有差异")
+
+	// CSV -> YAML -> 解析回来保持一致
+	snapshot, err := svc.Snapshot()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, snapshot.WriteYAML(&buf))
+	parsed, err := service.ReadPolicyYAML(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot, parsed)
+}
+
+func TestPermissionService_Diff_KeepsRoles(t *testing.T) {
+	e, err := casbin.NewSyncedEnforcer(provider.NewCasbinModel())
+	require.NoError(t, err)
+	svc := service.NewPermissionService(e)
+
+	_, err = svc.AssignRole(service.RoleAssignment{Subject: service.UserSubject(1), Role: "admin"})
+	require.NoError(t, err)
+	_, err = svc.AssignRole(service.RoleAssignment{Subject: service.UserSubject(2), Role: "sales"})
+	require.NoError(t, err)
+
+	// 与 deploy/policies.csv 一样只有 p 规则
+	desired, err := service.ReadPolicyCSV(strings.NewReader("p, role:admin, /api/admin/*, *\n"))
+	require.NoError(t, err)
+
+	diff, err := svc.Diff(desired, service.DiffOptions{Prune: true})
+	require.NoError(t, err)
+	assert.Empty(t, diff.RemoveRoles, "--prune 不删除角色分配")
+
+	_, err = svc.Diff(desired, service.DiffOptions{Prune: true, PruneRoles: true})
+	assert.ErrorIs(t, err, service.ErrLastAdmin)
+
+	// 文件里保留了管理员时可以删除其他角色分配
+	desired.Roles = []service.RoleAssignment{{Subject: service.UserSubject(1), Role: "admin"}}
+	diff, err = svc.Diff(desired, service.DiffOptions{PruneRoles: true})
+	require.NoError(t, err)
+	assert.Equal(t, []service.RoleAssignment{{Subject: "user:2", Role: "role:sales"}}, diff.RemoveRoles)
+}
+
+func TestPermissionService_KeepsLastAdmin(t *testing.T) {
+	e, err := casbin.NewSyncedEnforcer(provider.NewCasbinModel())
+	require.NoError(t, err)
+	svc := service.NewPermissionService(e)
+
+	for _, id := range []uint{1, 2} {
+		_, err = svc.AssignRole(service.RoleAssignment{Subject: service.UserSubject(id), Role: "admin"})
+		require.NoError(t, err)
+	}
+	_, err = svc.AssignRole(service.RoleAssignment{Subject: service.UserSubject(3), Role: "sales"})
+	require.NoError(t, err)
+
+	removed, err := svc.UnassignRole(service.RoleAssignment{Subject: service.UserSubject(1), Role: "role:admin"})
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	_, err = svc.UnassignRole(service.RoleAssignment{Subject: service.UserSubject(2), Role: "admin"})
+	assert.ErrorIs(t, err, service.ErrLastAdmin)
+	assert.ErrorIs(t, err, errs.ErrConflict, "渲染为 409")
+	_, err = svc.RemoveSubject(service.UserSubject(2))
+	assert.ErrorIs(t, err, service.ErrLastAdmin)
+
+	roles, err := svc.RolesFor(service.UserSubject(2))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"role:admin"}, roles)
+
+	// 非管理员不受影响
+	removed, err = svc.RemoveSubject(service.UserSubject(3))
+	require.NoError(t, err)
+	assert.True(t, removed)
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicySet 一份完整的 RBAC 策略 (可来自 CSV/YAML 文件，也可以是当前 casbin_rule 表的快照)
+type PolicySet struct {
+	Policies []Policy         `yaml:"policies"`
+	Roles    []RoleAssignment `yaml:"roles"`
+}
+
+// PolicyDiff 期望策略与当前策略的差异
+type PolicyDiff struct {
+	AddPolicies    []Policy
+	RemovePolicies []Policy
+	AddRoles       []RoleAssignment
+	RemoveRoles    []RoleAssignment
+}
+
+// Empty 是否没有任何变更
+func (d *PolicyDiff) Empty() bool {
+	return len(d.AddPolicies)+len(d.RemovePolicies)+len(d.AddRoles)+len(d.RemoveRoles) == 0
+}
+
+// LoadPolicyFile 按扩展名读取 .csv / .yaml / .yml 策略文件
+//
+// CSV 与 Casbin 官方格式一致:
+//
+//	p, role:sales, /api/orders/:id, GET
+//	g, user:1, role:sales
+//
+// YAML:
+//
+//	policies:
+//	  - {sub: role:sales, obj: /api/orders/:id, act: GET}
+//	roles:
+//	  - {sub: user:1, role: sales}
+func LoadPolicyFile(path string) (*PolicySet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var set *PolicySet
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		set, err = ReadPolicyCSV(f)
+	case ".yaml", ".yml":
+		set, err = ReadPolicyYAML(f)
+	default:
+		return nil, fmt.Errorf("unsupported policy file %q (want .csv, .yaml or .yml)", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+// ReadPolicyCSV 解析 Casbin CSV 格式
+func ReadPolicyCSV(r io.Reader) (*PolicySet, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	set := &PolicySet{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		switch strings.TrimSpace(record[0]) {
+		case "p":
+			if len(record) != 4 {
+				return nil, fmt.Errorf("line %d: p rule needs 3 fields: p, sub, obj, act", line)
+			}
+			set.Policies = append(set.Policies, Policy{Subject: record[1], Object: record[2], Action: record[3]})
+		case "g":
+			if len(record) != 3 {
+				return nil, fmt.Errorf("line %d: g rule needs 2 fields: g, sub, role", line)
+			}
+			set.Roles = append(set.Roles, RoleAssignment{Subject: record[1], Role: record[2]})
+		default:
+			return nil, fmt.Errorf("line %d: unknown rule type %q", line, record[0])
+		}
+	}
+	return set.normalize()
+}
+
+// ReadPolicyYAML 解析 YAML 格式
+func ReadPolicyYAML(r io.Reader) (*PolicySet, error) {
+	var raw struct {
+		Policies []struct {
+			Sub string `yaml:"sub"`
+			Obj string `yaml:"obj"`
+			Act string `yaml:"act"`
+		} `yaml:"policies"`
+		Roles []struct {
+			Sub  string `yaml:"sub"`
+			Role string `yaml:"role"`
+		} `yaml:"roles"`
+	}
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	set := &PolicySet{}
+	for _, p := range raw.Policies {
+		set.Policies = append(set.Policies, Policy{Subject: p.Sub, Object: p.Obj, Action: p.Act})
+	}
+	for _, g := range raw.Roles {
+		set.Roles = append(set.Roles, RoleAssignment{Subject: g.Sub, Role: g.Role})
+	}
+	return set.normalize()
+}
+
+// WriteCSV 以 Casbin CSV 格式输出
+func (ps *PolicySet) WriteCSV(w io.Writer) error {
+	for _, p := range ps.Policies {
+		if _, err := fmt.Fprintf(w, "p, %s, %s, %s\n", p.Subject, p.Object, p.Action); err != nil {
+			return err
+		}
+	}
+	for _, g := range ps.Roles {
+		if _, err := fmt.Fprintf(w, "g, %s, %s\n", g.Subject, g.Role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteYAML 以 YAML 格式输出
+func (ps *PolicySet) WriteYAML(w io.Writer) error {
+	type policy struct {
+		Sub string `yaml:"sub"`
+		Obj string `yaml:"obj"`
+		Act string `yaml:"act"`
+	}
+	type role struct {
+		Sub  string `yaml:"sub"`
+		Role string `yaml:"role"`
+	}
+	out := struct {
+		Policies []policy `yaml:"policies"`
+		Roles    []role   `yaml:"roles"`
+	}{Policies: []policy{}, Roles: []role{}}
+	for _, p := range ps.Policies {
+		out.Policies = append(out.Policies, policy{p.Subject, p.Object, p.Action})
+	}
+	for _, g := range ps.Roles {
+		out.Roles = append(out.Roles, role{g.Subject, g.Role})
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// normalize 统一格式 (去空格、方法大写、角色补 role: 前缀) 并校验主体，方便与数据库比对
+func (ps *PolicySet) normalize() (*PolicySet, error) {
+	for i := range ps.Policies {
+		p := &ps.Policies[i]
+		p.Subject = strings.TrimSpace(p.Subject)
+		p.Object = strings.TrimSpace(p.Object)
+		p.Action = strings.ToUpper(strings.TrimSpace(p.Action))
+		if p.Object == "" || p.Action == "" {
+			return nil, fmt.Errorf("policy %q: obj and act are required", p.Subject)
+		}
+		if err := validateSubject(p.Subject); err != nil {
+			return nil, fmt.Errorf("policy %q: %w", p.Subject, err)
+		}
+	}
+	for i := range ps.Roles {
+		g := &ps.Roles[i]
+		g.Subject = strings.TrimSpace(g.Subject)
+		g.Role = RoleSubject(strings.TrimSpace(g.Role))
+		if err := validateSubject(g.Subject); err != nil {
+			return nil, fmt.Errorf("role assignment %q: %w", g.Subject, err)
+		}
+	}
+	ps.sort()
+	return ps, nil
+}
+
+func (ps *PolicySet) sort() {
+	sort.Slice(ps.Policies, func(i, j int) bool {
+		a, b := ps.Policies[i], ps.Policies[j]
+		return a.Subject+"\x00"+a.Object+"\x00"+a.Action < b.Subject+"\x00"+b.Object+"\x00"+b.Action
+	})
+	sort.Slice(ps.Roles, func(i, j int) bool {
+		a, b := ps.Roles[i], ps.Roles[j]
+		return a.Subject+"\x00"+a.Role < b.Subject+"\x00"+b.Role
+	})
+}
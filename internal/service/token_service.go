@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/pkg/auth"
+	"go-artisan/pkg/errs"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrInvalidRefreshToken = errs.Unauthorized(40102, "invalid or expired refresh token")
+	// ErrRefreshTokenReused 已使用过的 Refresh Token 被再次提交，说明可能被盗用
+	ErrRefreshTokenReused = errs.Unauthorized(40103, "refresh token reuse detected")
+	ErrInvalidToken       = errs.Unauthorized(40104, "invalid or expired token")
+	ErrTokenRevoked       = errs.Unauthorized(40105, "token has been revoked")
+)
+
+// markRefreshUsed 标记 Refresh Token 已使用：返回 1 表示本次标记成功，0 表示已被使用过，-1 表示已过期
+// 直接 HSETNX 会在 Key 过期后重新创建一个没有 TTL 的 Hash，因此先确认 Key 仍然存在
+var markRefreshUsed = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+return redis.call("HSETNX", KEYS[1], "used_at", ARGV[1])
+`)
+
+// TokenPair 一次签发的 Access + Refresh Token
+type TokenPair struct {
+	AccessToken      string `json:"token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+
+	Scope string `json:"-"` // 授权范围，OAuth2 Token 响应需要回显
+}
+
+// TokenService 负责 Token 的签发、轮换与吊销
+// Refresh Token 是不透明随机串，Redis 里只存它的 SHA-256，数据库泄露也无法还原
+//
+// Redis 结构:
+//
+//	auth:refresh:<sha256>      Hash {user_id, family, gen, scope, client_id, auth_time, used_at}  TTL = refresh_ttl
+//	auth:refresh_family:<id>   String user_id                        TTL = refresh_ttl (每次轮换续期)
+//	auth:revoked:<jti>         String "1"                            TTL = Access Token 剩余寿命
+//	auth:user_gen:<user_id>    Int 用户 Token 代数                   永久 (用户被清除后过期)
+//
+// 同一次登录派生出的所有 Refresh Token 属于同一个 family，
+// 一旦检测到重放，直接删除 family，整条链上的 Token 全部失效。
+// "全部登出" 通过递增用户代数实现：代数小于当前值的 Access/Refresh Token 一律拒绝。
+type TokenService struct {
+	config *config.Config
+	redis  *redis.Client
+	opts   auth.Options
+}
+
+// NewTokenService 启动时加载一次签名密钥，密钥文件有误直接拒绝启动
+func NewTokenService(cfg *config.Config, rdb *redis.Client) (*TokenService, error) {
+	keys, err := auth.LoadKeySet(cfg.Auth.KeySetConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenService{
+		config: cfg,
+		redis:  rdb,
+		opts: auth.Options{
+			Keys:      keys,
+			TTL:       cfg.Auth.TTL,
+			Issuer:    cfg.Auth.Issuer,
+			Audience:  cfg.Auth.Audience,
+			ClockSkew: cfg.Auth.ClockSkew,
+		},
+	}, nil
+}
+
+// JWKS 当前可用于验签的公钥集合，供其他服务验证我们签发的 Token
+func (s *TokenService) JWKS() auth.JWKS {
+	return s.opts.Keys.JWKS()
+}
+
+// Issue 登录成功后签发一对新 Token (开启一个新的 family)
+// scopes 限定 Token 的权限范围，轮换时原样继承；账号密码登录传 auth.ScopeAll
+func (s *TokenService) Issue(ctx context.Context, userID uint, scopes ...string) (*TokenPair, error) {
+	return s.startFamily(ctx, userID, "", auth.JoinScope(scopes))
+}
+
+// IssueForClient OAuth2 授权码换取 Token：Token 绑定客户端，Refresh Token 只能由同一客户端轮换
+func (s *TokenService) IssueForClient(ctx context.Context, userID uint, clientID string, scopes []string) (*TokenPair, error) {
+	return s.startFamily(ctx, userID, clientID, auth.JoinScope(scopes))
+}
+
+// IssueClientToken client_credentials 授权：Token 不代表任何用户 (user_id 为 0)，
+// 按 RFC 6749 4.4.3 不签发 Refresh Token
+func (s *TokenService) IssueClientToken(clientID string, scopes []string) (*TokenPair, error) {
+	accessToken, err := auth.GenerateToken(0, s.opts,
+		auth.WithClient(clientID),
+		auth.WithScopes(scopes...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   int(s.config.Auth.TTL.Seconds()),
+		Scope:       auth.JoinScope(scopes),
+	}, nil
+}
+
+// Refresh 用 Refresh Token 换一对新 Token，旧的 Refresh Token 立即作废
+// OAuth2 客户端的 Refresh Token 不能在这里使用，必须走 RefreshForClient
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	return s.rotate(ctx, refreshToken, "")
+}
+
+// RefreshForClient OAuth2 refresh_token 授权：Refresh Token 必须属于 clientID
+func (s *TokenService) RefreshForClient(ctx context.Context, refreshToken, clientID string) (*TokenPair, error) {
+	return s.rotate(ctx, refreshToken, clientID)
+}
+
+// RefreshTokenInfo Refresh Token 的元数据，用于令牌自省与吊销
+type RefreshTokenInfo struct {
+	UserID    uint
+	ClientID  string
+	Family    string
+	Scope     string
+	ExpiresAt time.Time
+
+	gen      int64
+	authTime time.Time
+	used     bool
+}
+
+// LookupRefresh 查询仍然可用的 Refresh Token，已使用、已撤销或已过期的一律返回 ErrInvalidRefreshToken
+func (s *TokenService) LookupRefresh(ctx context.Context, refreshToken string) (*RefreshTokenInfo, error) {
+	info, err := s.lookup(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if info.used {
+		return nil, ErrInvalidRefreshToken
+	}
+	return info, nil
+}
+
+func (s *TokenService) rotate(ctx context.Context, refreshToken, clientID string) (*TokenPair, error) {
+	info, err := s.lookup(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	// 在标记使用之前校验客户端，别的客户端拿到 Token 也无法把它消耗掉
+	if info.ClientID != clientID {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 脚本原子执行：并发提交同一个 Token 时只有一个请求能标记成功
+	marked, err := markRefreshUsed.Run(ctx, s.redis, []string{refreshKey(refreshToken)}, time.Now().Unix()).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark refresh token: %w", err)
+	}
+	if marked < 0 {
+		// 查询之后恰好过期
+		return nil, ErrInvalidRefreshToken
+	}
+	if marked == 0 {
+		// 重放：撤销整个 family，合法用户和攻击者都需要重新登录
+		if err := s.RevokeFamily(ctx, info.Family); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	// 续期 family，保证活跃会话不会在中途断掉
+	if err := s.redis.Expire(ctx, familyKey(info.Family), s.config.Auth.RefreshTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to extend token family: %w", err)
+	}
+
+	return s.issuePair(ctx, info.UserID, info.Family, info.gen, info.Scope, info.ClientID, info.authTime)
+}
+
+// lookup 读取 Refresh Token 并校验代数与 family，是否已使用由调用方决定如何处理
+func (s *TokenService) lookup(ctx context.Context, refreshToken string) (*RefreshTokenInfo, error) {
+	key := refreshKey(refreshToken)
+
+	pipe := s.redis.Pipeline()
+	hash := pipe.HGetAll(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load refresh token: %w", err)
+	}
+	fields := hash.Val()
+	if len(fields) == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	userID, err := strconv.ParseUint(fields["user_id"], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	tokenGen, _ := strconv.ParseInt(fields["gen"], 10, 64)
+	info := &RefreshTokenInfo{
+		UserID:    uint(userID),
+		ClientID:  fields["client_id"],
+		Family:    fields["family"],
+		Scope:     fields["scope"],
+		ExpiresAt: time.Now().Add(ttl.Val()),
+		used:      fields["used_at"] != "",
+	}
+	// 引入 scope 之前写入的第一方 Refresh Token 没有 scope 字段，轮换后仍应拥有全部权限
+	if _, ok := fields["scope"]; !ok && info.ClientID == "" {
+		info.Scope = auth.ScopeAll
+	}
+	// 旧的 Refresh Token 没有 auth_time，登录时间未知，按很久以前处理
+	if authTime, err := strconv.ParseInt(fields["auth_time"], 10, 64); err == nil {
+		info.authTime = time.Unix(authTime, 0)
+	}
+
+	// 用户执行过 "全部登出"，旧代数的 Refresh Token 不再可用
+	gen, err := s.generation(ctx, info.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if tokenGen < gen {
+		return nil, ErrInvalidRefreshToken
+	}
+	info.gen = gen
+
+	// family 已被撤销 (登出或检测到重放)
+	alive, err := s.redis.Exists(ctx, familyKey(info.Family)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token family: %w", err)
+	}
+	if alive == 0 {
+		return nil, ErrInvalidRefreshToken
+	}
+	return info, nil
+}
+
+// Authenticate 校验 Access Token 的签名、有效期以及是否已被吊销
+func (s *TokenService) Authenticate(ctx context.Context, tokenString string) (*auth.Claims, error) {
+	claims, err := auth.ParseToken(tokenString, s.opts)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	pipe := s.redis.Pipeline()
+	revoked := pipe.Exists(ctx, revokedKey(claims.ID))
+	gen := pipe.Get(ctx, generationKey(claims.UserID))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+
+	if revoked.Val() > 0 {
+		return nil, ErrTokenRevoked
+	}
+	if current, _ := gen.Int64(); claims.Generation < current {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// Revoke 登出当前会话：Access Token 的 jti 进入黑名单，所属 Refresh Token family 一并撤销
+func (s *TokenService) Revoke(ctx context.Context, claims *auth.Claims) error {
+	// 黑名单只需要保留到 Token 自然过期 (加上允许的时钟偏差)
+	ttl := time.Until(claims.ExpiresAt.Time) + s.config.Auth.ClockSkew
+	if ttl > 0 {
+		if err := s.redis.Set(ctx, revokedKey(claims.ID), 1, ttl).Err(); err != nil {
+			return fmt.Errorf("failed to revoke token: %w", err)
+		}
+	}
+
+	if claims.SessionID != "" {
+		return s.RevokeFamily(ctx, claims.SessionID)
+	}
+	return nil
+}
+
+// RevokeAll 全部登出：递增用户代数，此前签发的所有 Token 立即失效
+func (s *TokenService) RevokeAll(ctx context.Context, userID uint) error {
+	if err := s.redis.Incr(ctx, generationKey(userID)).Err(); err != nil {
+		return fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	return nil
+}
+
+// ForgetUser 用户被清除后回收其代数键
+// 不能直接删除：代数归零会让尚未过期的旧 Refresh Token 重新生效，因此等所有 Token 都过期后再由 Redis 删除
+func (s *TokenService) ForgetUser(ctx context.Context, userID uint) error {
+	ttl := max(s.config.Auth.RefreshTTL, s.config.Auth.TTL) + s.config.Auth.ClockSkew
+	if err := s.redis.Expire(ctx, generationKey(userID), ttl).Err(); err != nil {
+		return fmt.Errorf("failed to expire user token generation: %w", err)
+	}
+	return nil
+}
+
+// RevokeFamily 撤销一次登录派生出的全部 Refresh Token
+func (s *TokenService) RevokeFamily(ctx context.Context, family string) error {
+	if err := s.redis.Del(ctx, familyKey(family)).Err(); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+	return nil
+}
+
+// startFamily 开启一个新的 family 并签发第一对 Token
+func (s *TokenService) startFamily(ctx context.Context, userID uint, clientID, scope string) (*TokenPair, error) {
+	gen, err := s.generation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	family := uuid.NewString()
+	if err := s.redis.Set(ctx, familyKey(family), userID, s.config.Auth.RefreshTTL).Err(); err != nil {
+		return nil, fmt.Errorf("failed to create token family: %w", err)
+	}
+	// 只有第一方登录是用户刚输入过凭证；OAuth2 客户端换取 Token 时用户可能早已登录，不记录登录时间
+	var authTime time.Time
+	if clientID == "" {
+		authTime = time.Now()
+	}
+	return s.issuePair(ctx, userID, family, gen, scope, clientID, authTime)
+}
+
+func (s *TokenService) issuePair(ctx context.Context, userID uint, family string, gen int64, scope, clientID string, authTime time.Time) (*TokenPair, error) {
+	claimOpts := []auth.ClaimOption{
+		auth.WithSession(family),
+		auth.WithGeneration(gen),
+		auth.WithScopes(auth.ParseScope(scope)...),
+		auth.WithClient(clientID),
+	}
+	if !authTime.IsZero() {
+		claimOpts = append(claimOpts, auth.WithAuthTime(authTime))
+	}
+	accessToken, err := auth.GenerateToken(userID, s.opts, claimOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := refreshKey(refreshToken)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "family", family, "gen", gen, "scope", scope, "client_id", clientID)
+	if !authTime.IsZero() {
+		pipe.HSet(ctx, key, "auth_time", authTime.Unix())
+	}
+	pipe.Expire(ctx, key, s.config.Auth.RefreshTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int(s.config.Auth.TTL.Seconds()),
+		RefreshExpiresIn: int(s.config.Auth.RefreshTTL.Seconds()),
+		Scope:            scope,
+	}, nil
+}
+
+// generation 读取用户当前的 Token 代数，从未 "全部登出" 过的用户为 0
+func (s *TokenService) generation(ctx context.Context, userID uint) (int64, error) {
+	gen, err := s.redis.Get(ctx, generationKey(userID)).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, fmt.Errorf("failed to load token generation: %w", err)
+	}
+	return gen, nil
+}
+
+// randomToken 生成 256 bit 的不透明随机串
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken 只持久化 Token 的摘要
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func refreshKey(token string) string {
+	return "auth:refresh:" + hashToken(token)
+}
+
+func familyKey(family string) string {
+	return "auth:refresh_family:" + family
+}
+
+func revokedKey(jti string) string {
+	return "auth:revoked:" + jti
+}
+
+func generationKey(userID uint) string {
+	return fmt.Sprintf("auth:user_gen:%d", userID)
+}
@@ -0,0 +1,190 @@
+package service_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/service"
+	"go-artisan/pkg/auth"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTokenService(t *testing.T) *service.TokenService {
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Secret:     "test-secret",
+			TTL:        15 * time.Minute,
+			RefreshTTL: 24 * time.Hour,
+			Issuer:     "go-artisan",
+		},
+	}
+	svc, err := service.NewTokenService(cfg, rdb)
+	require.NoError(t, err)
+	return svc
+}
+
+func TestTokenService_Refresh(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("轮换：旧 Refresh Token 换出新的一对", func(t *testing.T) {
+		svc := newTestTokenService(t)
+
+		first, err := svc.Issue(ctx, 7)
+		require.NoError(t, err)
+
+		second, err := svc.Refresh(ctx, first.RefreshToken)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+		assert.NotEmpty(t, second.AccessToken)
+
+		// 新 Token 可以继续轮换
+		_, err = svc.Refresh(ctx, second.RefreshToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("轮换：scope 原样继承", func(t *testing.T) {
+		svc := newTestTokenService(t)
+
+		first, err := svc.Issue(ctx, 7, "orders:read", "orders:write")
+		require.NoError(t, err)
+		second, err := svc.Refresh(ctx, first.RefreshToken)
+		require.NoError(t, err)
+
+		claims, err := svc.Authenticate(ctx, second.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"orders:read", "orders:write"}, claims.Scopes())
+	})
+
+	t.Run("轮换：旧版本写入的 Refresh Token 没有 scope 字段，按全部权限处理", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		svc, err := service.NewTokenService(&config.Config{Auth: config.AuthConfig{
+			Secret: "test-secret", TTL: 15 * time.Minute, RefreshTTL: 24 * time.Hour, Issuer: "go-artisan",
+		}}, redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		require.NoError(t, err)
+
+		first, err := svc.Issue(ctx, 7, auth.ScopeAll)
+		require.NoError(t, err)
+		for _, key := range mr.Keys() {
+			if strings.HasPrefix(key, "auth:refresh:") {
+				mr.HDel(key, "scope")
+			}
+		}
+
+		second, err := svc.Refresh(ctx, first.RefreshToken)
+		require.NoError(t, err)
+		claims, err := svc.Authenticate(ctx, second.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []string{auth.ScopeAll}, claims.Scopes())
+		assert.Equal(t, auth.ScopeAll, claims.Scope)
+	})
+
+	t.Run("轮换：登录时间 (auth_time) 保持不变，旧 Token 没有时不写入", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		svc, err := service.NewTokenService(&config.Config{Auth: config.AuthConfig{
+			Secret: "test-secret", TTL: 15 * time.Minute, RefreshTTL: 24 * time.Hour, Issuer: "go-artisan",
+		}}, redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		require.NoError(t, err)
+
+		loggedIn := time.Now().Add(-time.Hour).Truncate(time.Second)
+		first, err := svc.Issue(ctx, 7, auth.ScopeAll)
+		require.NoError(t, err)
+		for _, key := range mr.Keys() {
+			if strings.HasPrefix(key, "auth:refresh:") {
+				mr.HSet(key, "auth_time", strconv.FormatInt(loggedIn.Unix(), 10))
+			}
+		}
+
+		second, err := svc.Refresh(ctx, first.RefreshToken)
+		require.NoError(t, err)
+		claims, err := svc.Authenticate(ctx, second.AccessToken)
+		require.NoError(t, err)
+		require.NotNil(t, claims.AuthTime)
+		assert.True(t, loggedIn.Equal(claims.AuthTime.Time))
+
+		for _, key := range mr.Keys() {
+			if strings.HasPrefix(key, "auth:refresh:") {
+				mr.HDel(key, "auth_time")
+			}
+		}
+		third, err := svc.Refresh(ctx, second.RefreshToken)
+		require.NoError(t, err)
+		claims, err = svc.Authenticate(ctx, third.AccessToken)
+		require.NoError(t, err)
+		assert.Nil(t, claims.AuthTime)
+	})
+
+	t.Run("重放：已使用的 Token 再次提交会撤销整个 family", func(t *testing.T) {
+		svc := newTestTokenService(t)
+
+		first, err := svc.Issue(ctx, 7)
+		require.NoError(t, err)
+		second, err := svc.Refresh(ctx, first.RefreshToken)
+		require.NoError(t, err)
+
+		_, err = svc.Refresh(ctx, first.RefreshToken)
+		assert.ErrorIs(t, err, service.ErrRefreshTokenReused)
+
+		// 同一 family 下尚未使用的新 Token 也随之失效
+		_, err = svc.Refresh(ctx, second.RefreshToken)
+		assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+	})
+
+	t.Run("未知 Token", func(t *testing.T) {
+		svc := newTestTokenService(t)
+
+		_, err := svc.Refresh(ctx, "not-a-real-token")
+		assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+	})
+}
+
+func TestTokenService_Revoke(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("登出：当前 Access Token 与 Refresh Token 失效", func(t *testing.T) {
+		svc := newTestTokenService(t)
+
+		pair, err := svc.Issue(ctx, 7)
+		require.NoError(t, err)
+		claims, err := svc.Authenticate(ctx, pair.AccessToken)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.Revoke(ctx, claims))
+
+		_, err = svc.Authenticate(ctx, pair.AccessToken)
+		assert.ErrorIs(t, err, service.ErrTokenRevoked)
+		_, err = svc.Refresh(ctx, pair.RefreshToken)
+		assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+	})
+
+	t.Run("全部登出：旧 Token 失效，新登录不受影响", func(t *testing.T) {
+		svc := newTestTokenService(t)
+
+		phone, err := svc.Issue(ctx, 7)
+		require.NoError(t, err)
+		laptop, err := svc.Issue(ctx, 7)
+		require.NoError(t, err)
+
+		require.NoError(t, svc.RevokeAll(ctx, 7))
+
+		for _, pair := range []*service.TokenPair{phone, laptop} {
+			_, err = svc.Authenticate(ctx, pair.AccessToken)
+			assert.ErrorIs(t, err, service.ErrTokenRevoked)
+			_, err = svc.Refresh(ctx, pair.RefreshToken)
+			assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+		}
+
+		fresh, err := svc.Issue(ctx, 7)
+		require.NoError(t, err)
+		_, err = svc.Authenticate(ctx, fresh.AccessToken)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/pkg/crypt"
+	"go-artisan/pkg/errs"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	recoveryCodeCount      = 8
+	maxMFAChallengeAttempt = 5
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errs.Conflict(40902, "two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errs.Conflict(40903, "two-factor authentication is not enabled")
+	ErrTwoFactorNotPending     = errs.Conflict(40904, "two-factor authentication has not been set up, enable it first")
+	ErrInvalidTwoFactorCode    = errs.Validation(42204, "invalid two-factor authentication code").WithField("code")
+	// ErrInvalidMFAToken 两步登录的挑战 Token 不存在、已过期或尝试次数过多
+	ErrInvalidMFAToken = errs.Unauthorized(40107, "invalid or expired MFA challenge, please login again")
+)
+
+// TwoFactorSetup 开启两步验证时返回给客户端的信息
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`      // 无法扫码时手动输入
+	URI    string `json:"otpauth_uri"` // 前端据此生成二维码
+}
+
+// TwoFactorService TOTP 两步验证 (RFC 6238)
+//
+// 开启流程：Enable 生成密钥 (未确认) -> 用户扫码 -> Confirm 提交一次验证码后正式开启并返回恢复码
+// 登录流程：密码正确且已开启两步验证时，Login 只返回挑战 Token，
+// 客户端再用挑战 Token + 验证码 (或恢复码) 换取最终的 JWT
+//
+// Redis 结构:
+//
+//	auth:mfa:<sha256>              Hash {user_id, attempts}  TTL = mfa_challenge_ttl
+//	auth:totp_used:<uid>:<code>    防止同一个验证码在有效窗口内被重放
+type TwoFactorService struct {
+	users    domain.UserRepository
+	codes    domain.RecoveryCodeRepository
+	throttle *LoginThrottle
+	config   *config.Config
+	redis    *redis.Client
+	crypt    *crypt.Encrypter
+}
+
+func NewTwoFactorService(users domain.UserRepository, codes domain.RecoveryCodeRepository, throttle *LoginThrottle, cfg *config.Config, rdb *redis.Client) (*TwoFactorService, error) {
+	enc, err := crypt.New(cfg.App.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorService{users: users, codes: codes, throttle: throttle, config: cfg, redis: rdb, crypt: enc}, nil
+}
+
+// Enable 生成新的 TOTP 密钥，需要 Confirm 之后才真正生效
+func (s *TwoFactorService) Enable(ctx context.Context, userID uint) (*TwoFactorSetup, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.config.App.Name,
+		AccountName: user.Email,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	encrypted, err := s.crypt.Encrypt(key.Secret())
+	if err != nil {
+		return nil, err
+	}
+	user.TwoFactorSecret = encrypted
+	user.TwoFactorConfirmedAt = nil
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{Secret: key.Secret(), URI: key.URL()}, nil
+}
+
+// Confirm 校验用户扫码后的第一个验证码，通过后正式开启并返回一次性展示的恢复码
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TwoFactorSecret == "" {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	ok, err := s.validateTOTP(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	now := time.Now()
+	user.TwoFactorConfirmedAt = &now
+	if err := s.users.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	s.redis.Del(ctx, profileCacheKey(user.ID))
+
+	return s.replaceRecoveryCodes(ctx, user.ID)
+}
+
+// Disable 关闭两步验证 (需要验证码或恢复码，防止会话被盗后直接关闭)
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint, code string) error {
+	user, err := s.requireEnabled(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+
+	user.TwoFactorSecret = ""
+	user.TwoFactorConfirmedAt = nil
+	if err := s.users.Update(ctx, user); err != nil {
+		return err
+	}
+	s.redis.Del(ctx, profileCacheKey(user.ID))
+	return s.codes.DeleteByUserID(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes 作废旧恢复码并生成新的一批
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.requireEnabled(ctx, userID, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, user.ID)
+}
+
+// Verify 已登录用户用验证码或恢复码再次认证 (例如注销账户)，恢复码校验通过即被消耗
+func (s *TwoFactorService) Verify(ctx context.Context, user *domain.User, code string) (bool, error) {
+	if !user.TwoFactorEnabled() {
+		return false, ErrTwoFactorNotEnabled
+	}
+	return s.verifyCode(ctx, user, code)
+}
+
+// Challenge 密码校验通过后创建两步登录挑战，返回给客户端的挑战 Token
+func (s *TwoFactorService) Challenge(ctx context.Context, userID uint) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	key := mfaChallengeKey(token)
+	pipe := s.redis.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "attempts", 0)
+	pipe.Expire(ctx, key, s.config.Auth.MFAChallengeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	return token, nil
+}
+
+// ChallengeUserID 挑战所属的用户，不消耗尝试次数；挑战不存在或已过期时返回 ErrInvalidMFAToken
+func (s *TwoFactorService) ChallengeUserID(ctx context.Context, mfaToken string) (uint, error) {
+	uidStr, err := s.redis.HGet(ctx, mfaChallengeKey(mfaToken), "user_id").Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load mfa challenge: %w", err)
+	}
+	uid, err := strconv.ParseUint(uidStr, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMFAToken
+	}
+	return uint(uid), nil
+}
+
+// VerifyChallenge 校验挑战 Token 与验证码 (或恢复码)，成功返回用户 ID，挑战随即作废
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, mfaToken, code string) (uint, error) {
+	key := mfaChallengeKey(mfaToken)
+
+	// 先计数再校验，超过次数直接作废挑战，防止在有效期内暴力枚举 6 位验证码
+	attempts, err := s.redis.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load mfa challenge: %w", err)
+	}
+	uidStr, err := s.redis.HGet(ctx, key, "user_id").Result()
+	if errors.Is(err, redis.Nil) {
+		s.redis.Del(ctx, key) // HIncrBy 对不存在的 key 会创建一个没有 TTL 的 Hash
+		return 0, ErrInvalidMFAToken
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load mfa challenge: %w", err)
+	}
+	if attempts > maxMFAChallengeAttempt {
+		s.redis.Del(ctx, key)
+		return 0, ErrInvalidMFAToken
+	}
+
+	uid, err := strconv.ParseUint(uidStr, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMFAToken
+	}
+	user, err := s.users.FindByID(ctx, uint(uid))
+	if err != nil {
+		return 0, err
+	}
+	if !user.TwoFactorEnabled() {
+		return 0, ErrInvalidMFAToken
+	}
+
+	ok, err := s.verifyCode(ctx, user, code)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, ErrInvalidTwoFactorCode
+	}
+
+	// 挑战只能成功使用一次
+	deleted, err := s.redis.Del(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if deleted == 0 {
+		return 0, ErrInvalidMFAToken
+	}
+	return user.ID, nil
+}
+
+func (s *TwoFactorService) requireEnabled(ctx context.Context, userID uint, code string) (*domain.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	// 与确认当前密码共用按用户的失败计数，拿到会话的攻击者不能无限次猜验证码
+	err = throttledReauth(ctx, s.throttle, user.ID, ErrInvalidTwoFactorCode, func() (bool, error) {
+		return s.verifyCode(ctx, user, code)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// verifyCode 6 位数字按 TOTP 校验，其余按恢复码校验
+func (s *TwoFactorService) verifyCode(ctx context.Context, user *domain.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 && isDigits(code) {
+		return s.validateTOTP(ctx, user, code)
+	}
+	return s.codes.Consume(ctx, user.ID, hashToken(normalizeRecoveryCode(code)))
+}
+
+func (s *TwoFactorService) validateTOTP(ctx context.Context, user *domain.User, code string) (bool, error) {
+	secret, err := s.crypt.Decrypt(user.TwoFactorSecret)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	// 允许前后各一个 30 秒窗口的时钟偏差
+	ok, err := totp.ValidateCustom(code, secret, time.Now(), totp.ValidateOpts{
+		Period:    30,
+		Skew:      1,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	})
+	if err != nil || !ok {
+		return false, nil
+	}
+
+	// 同一验证码在整个有效窗口 (3 * 30s) 内只能用一次
+	fresh, err := s.redis.SetNX(ctx, fmt.Sprintf("auth:totp_used:%d:%s", user.ID, code), 1, 90*time.Second).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp usage: %w", err)
+	}
+	return fresh, nil
+}
+
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+
+	if err := s.codes.ReplaceAll(ctx, userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 忽略大小写和连字符，方便用户手动输入
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func mfaChallengeKey(token string) string {
+	return "auth:mfa:" + hashToken(token)
+}
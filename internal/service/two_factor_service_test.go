@@ -0,0 +1,221 @@
+package service_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-artisan/internal/config"
+	"go-artisan/internal/domain"
+	"go-artisan/internal/domain/mocks"
+	"go-artisan/internal/service"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/pquerna/otp/totp"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTwoFactor_EnrollAndLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	codes := mocks.NewMockRecoveryCodeRepository(ctrl)
+
+	cfg := &config.Config{
+		App: config.AppConfig{Name: "GoArtisan", Key: "test-app-key"},
+		Auth: config.AuthConfig{
+			Secret:          "test-secret",
+			TTL:             time.Hour,
+			RefreshTTL:      24 * time.Hour,
+			MFAChallengeTTL: 5 * time.Minute,
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(cfg, rdb)
+	require.NoError(t, err)
+	twoFactor, err := service.NewTwoFactorService(users, codes, service.NewLoginThrottle(cfg, rdb), cfg, rdb)
+	require.NoError(t, err)
+	svc := service.NewUserService(users, nil, cfg, rdb, tokens, service.NewLoginThrottle(cfg, rdb), twoFactor, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	// 用户数据在 mock 里保持状态，模拟数据库
+	user := &domain.User{ID: 1, Email: "admin@example.com", Password: hashPassword(t, "secret123")}
+	users.EXPECT().FindByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+	users.EXPECT().FindByEmail(gomock.Any(), user.Email).Return(user, nil).AnyTimes()
+	users.EXPECT().Update(gomock.Any(), user).Return(nil).AnyTimes()
+
+	// 1. 开启：返回密钥与 otpauth URI，但尚未生效
+	setup, err := twoFactor.Enable(ctx, 1)
+	require.NoError(t, err)
+	assert.Contains(t, setup.URI, "otpauth://totp/")
+	assert.NotEqual(t, setup.Secret, user.TwoFactorSecret, "secret must be stored encrypted")
+	assert.False(t, user.TwoFactorEnabled())
+
+	// 2. 错误的验证码不能确认
+	_, err = twoFactor.Confirm(ctx, 1, "000000")
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+
+	// 3. 正确的验证码确认后生成恢复码，库里只存哈希
+	var storedHashes []string
+	codes.EXPECT().ReplaceAll(gomock.Any(), uint(1), gomock.Any()).DoAndReturn(func(_ context.Context, _ uint, hashes []string) error {
+		storedHashes = hashes
+		return nil
+	})
+	code, err := totp.GenerateCode(setup.Secret, time.Now())
+	require.NoError(t, err)
+	recovery, err := twoFactor.Confirm(ctx, 1, code)
+	require.NoError(t, err)
+	require.Len(t, recovery, len(storedHashes))
+	assert.NotContains(t, storedHashes, recovery[0])
+	assert.True(t, user.TwoFactorEnabled())
+
+	// 4. 密码正确时只返回挑战，不发 Token
+	res, err := svc.Login(ctx, service.LoginDTO{Email: user.Email, Password: "secret123"})
+	require.NoError(t, err)
+	assert.True(t, res.MFARequired)
+	assert.NotEmpty(t, res.MFAToken)
+	assert.Empty(t, res.Token)
+
+	// 5. 刚用过的 TOTP 验证码不能重放
+	_, err = svc.LoginTwoFactor(ctx, service.TwoFactorLoginDTO{MFAToken: res.MFAToken, Code: code})
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+
+	// 6. 恢复码可以完成登录，挑战随即作废
+	codes.EXPECT().Consume(gomock.Any(), uint(1), gomock.Any()).Return(true, nil)
+	final, err := svc.LoginTwoFactor(ctx, service.TwoFactorLoginDTO{MFAToken: res.MFAToken, Code: recovery[0]})
+	require.NoError(t, err)
+	assert.NotEmpty(t, final.Token)
+	assert.NotEmpty(t, final.RefreshToken)
+
+	_, err = svc.LoginTwoFactor(ctx, service.TwoFactorLoginDTO{MFAToken: res.MFAToken, Code: recovery[1]})
+	assert.ErrorIs(t, err, service.ErrInvalidMFAToken)
+}
+
+func TestTwoFactor_ChallengeAttemptsLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	codes := mocks.NewMockRecoveryCodeRepository(ctrl)
+	cfg := &config.Config{
+		App:  config.AppConfig{Key: "test-app-key"},
+		Auth: config.AuthConfig{MFAChallengeTTL: 5 * time.Minute},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	twoFactor, err := service.NewTwoFactorService(users, codes, service.NewLoginThrottle(cfg, rdb), cfg, rdb)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	now := time.Now()
+	user := &domain.User{ID: 1, TwoFactorSecret: "irrelevant", TwoFactorConfirmedAt: &now}
+	users.EXPECT().FindByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+	codes.EXPECT().Consume(gomock.Any(), uint(1), gomock.Any()).Return(false, nil).AnyTimes()
+
+	token, err := twoFactor.Challenge(ctx, 1)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		_, err = twoFactor.VerifyChallenge(ctx, token, "wrong-code")
+		assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+	}
+	// 超过次数后挑战作废，必须重新输入密码
+	_, err = twoFactor.VerifyChallenge(ctx, token, "wrong-code")
+	assert.ErrorIs(t, err, service.ErrInvalidMFAToken)
+}
+
+func TestTwoFactor_BadCodesLockAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	codes := mocks.NewMockRecoveryCodeRepository(ctrl)
+	cfg := &config.Config{
+		App: config.AppConfig{Key: "test-app-key"},
+		Auth: config.AuthConfig{
+			Secret:          "test-secret",
+			TTL:             time.Hour,
+			RefreshTTL:      24 * time.Hour,
+			MFAChallengeTTL: 5 * time.Minute,
+			LoginThrottle: config.LoginThrottleConfig{
+				MaxAccountAttempts: 3,
+				MaxIPAttempts:      100,
+				Window:             time.Minute,
+				LockoutDuration:    time.Minute,
+			},
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(cfg, rdb)
+	require.NoError(t, err)
+	twoFactor, err := service.NewTwoFactorService(users, codes, service.NewLoginThrottle(cfg, rdb), cfg, rdb)
+	require.NoError(t, err)
+	svc := service.NewUserService(users, nil, cfg, rdb, tokens, service.NewLoginThrottle(cfg, rdb), twoFactor, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	now := time.Now()
+	user := &domain.User{ID: 1, Email: "victim@example.com", Password: hashPassword(t, "secret123"), TwoFactorSecret: "irrelevant", TwoFactorConfirmedAt: &now}
+	users.EXPECT().FindByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+	users.EXPECT().FindByEmail(gomock.Any(), user.Email).Return(user, nil).AnyTimes()
+	// 锁定后不再校验恢复码
+	codes.EXPECT().Consume(gomock.Any(), uint(1), gomock.Any()).Return(false, nil).Times(3)
+
+	// 攻击者知道密码：每次都重新登录拿新挑战，密码正确也不会清空验证码的失败计数
+	var mfaToken string
+	for i := 1; i <= 3; i++ {
+		res, err := svc.Login(ctx, service.LoginDTO{Email: user.Email, Password: "secret123", IP: "10.0.0.1"})
+		require.NoError(t, err, "attempt %d", i)
+		require.True(t, res.MFARequired)
+		mfaToken = res.MFAToken
+
+		_, err = svc.LoginTwoFactor(ctx, service.TwoFactorLoginDTO{MFAToken: mfaToken, Code: "wrong-code", IP: "10.0.0.1"})
+		if i < 3 {
+			assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode, "attempt %d", i)
+		} else {
+			assert.ErrorIs(t, err, service.ErrAccountLocked)
+		}
+	}
+
+	// 锁定期间未过期的挑战和密码登录都被拒绝
+	_, err = svc.LoginTwoFactor(ctx, service.TwoFactorLoginDTO{MFAToken: mfaToken, Code: "recovery-code", IP: "10.0.0.2"})
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+	_, err = svc.Login(ctx, service.LoginDTO{Email: user.Email, Password: "secret123", IP: "10.0.0.2"})
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+}
+
+func TestTwoFactor_ManageBadCodesLockAccount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	users := mocks.NewMockUserRepository(ctrl)
+	codes := mocks.NewMockRecoveryCodeRepository(ctrl)
+	cfg := &config.Config{
+		App: config.AppConfig{Key: "test-app-key"},
+		Auth: config.AuthConfig{
+			LoginThrottle: config.LoginThrottleConfig{
+				MaxAccountAttempts: 3,
+				MaxIPAttempts:      100,
+				Window:             time.Minute,
+				LockoutDuration:    time.Minute,
+			},
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	twoFactor, err := service.NewTwoFactorService(users, codes, service.NewLoginThrottle(cfg, rdb), cfg, rdb)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	now := time.Now()
+	user := &domain.User{ID: 1, TwoFactorSecret: "irrelevant", TwoFactorConfirmedAt: &now}
+	users.EXPECT().FindByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+	// 锁定后不再校验恢复码
+	codes.EXPECT().Consume(gomock.Any(), uint(1), gomock.Any()).Return(false, nil).Times(3)
+
+	// 拿到会话的攻击者猜验证码：关闭两步验证和重新生成恢复码共用同一个计数
+	err = twoFactor.Disable(ctx, 1, "wrong-code")
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+	_, err = twoFactor.RegenerateRecoveryCodes(ctx, 1, "wrong-code")
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+	err = twoFactor.Disable(ctx, 1, "wrong-code")
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+
+	err = twoFactor.Disable(ctx, 1, "recovery-code")
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+	assert.True(t, user.TwoFactorEnabled())
+}
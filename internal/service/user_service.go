@@ -5,38 +5,93 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"go-artisan/internal/config"
 	"go-artisan/internal/domain"
-
 	"go-artisan/pkg/auth"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/hash"
+	"go-artisan/pkg/validator"
 
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
-	repo   domain.UserRepository
-	config *config.Config
-	redis  *redis.Client // 👈 新增依赖
+	repo      domain.UserRepository
+	apiKeys   domain.APIKeyRepository
+	config    *config.Config
+	redis     *redis.Client // 👈 新增依赖
+	tokens    *TokenService
+	throttle  *LoginThrottle
+	twoFactor *TwoFactorService
+	hasher    hash.Hasher
+	policy    *validator.PasswordPolicy
+	logger    *slog.Logger
 }
 
+var (
+	ErrEmailTaken         = errs.Conflict(40901, "email already taken").WithField("email")
+	ErrUserNotFound       = errs.NotFound(40401, "user not found")
+	ErrInvalidCredentials = errs.Unauthorized(40101, "invalid credentials") // 不区分邮箱不存在和密码错误，防止枚举
+	// ErrCurrentPasswordMismatch 修改密码/邮箱时提供的当前密码不正确
+	ErrCurrentPasswordMismatch = errs.Validation(42201, "current password is incorrect").WithField("current_password")
+	// ErrWeakPassword 新密码不符合密码策略，Message 为具体违反的规则
+	ErrWeakPassword = errs.Validation(42202, "password does not meet the password policy").WithField("password")
+)
+
 // LoginDTO 输入对象
 type LoginDTO struct {
 	Email    string
 	Password string
+	IP       string // 客户端 IP，用于按 IP 限制失败次数
 }
 
 // LoginResponse
+// 开启两步验证的账户只返回 MFARequired + MFAToken，需要再调用 LoginTwoFactor 换取 Token
 type LoginResponse struct {
-	Token     string       `json:"token"`
-	User      *domain.User `json:"user"`
-	ExpiresIn int          `json:"expires_in"`
+	Token            string       `json:"token,omitempty"`
+	RefreshToken     string       `json:"refresh_token,omitempty"`
+	User             *domain.User `json:"user,omitempty"`
+	ExpiresIn        int          `json:"expires_in,omitempty"`
+	RefreshExpiresIn int          `json:"refresh_expires_in,omitempty"`
+
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
 
-func NewUserService(repo domain.UserRepository, cfg *config.Config, rdb *redis.Client) *UserService {
-	return &UserService{repo: repo, config: cfg, redis: rdb}
+// TwoFactorLoginDTO 两步登录第二步的输入
+type TwoFactorLoginDTO struct {
+	MFAToken string
+	Code     string // 6 位 TOTP 验证码或恢复码
+	IP       string // 客户端 IP，验证码错误与密码错误一样计入登录失败
+}
+
+func NewUserService(
+	repo domain.UserRepository,
+	apiKeys domain.APIKeyRepository,
+	cfg *config.Config,
+	rdb *redis.Client,
+	tokens *TokenService,
+	throttle *LoginThrottle,
+	twoFactor *TwoFactorService,
+	hasher hash.Hasher,
+	policy *validator.PasswordPolicy,
+	logger *slog.Logger,
+) *UserService {
+	return &UserService{
+		repo:      repo,
+		apiKeys:   apiKeys,
+		config:    cfg,
+		redis:     rdb,
+		tokens:    tokens,
+		throttle:  throttle,
+		twoFactor: twoFactor,
+		hasher:    hasher,
+		policy:    policy,
+		logger:    logger,
+	}
 }
 
 // RegisterDTO 输入对象
@@ -46,15 +101,15 @@ type RegisterDTO struct {
 	Password string
 }
 
-func (s *UserService) Register(req RegisterDTO) (*domain.User, error) {
+func (s *UserService) Register(ctx context.Context, req RegisterDTO) (*domain.User, error) {
 	// 1. 检查邮箱
-	existing, _ := s.repo.FindByEmail(req.Email)
+	existing, _ := s.repo.FindByEmail(ctx, req.Email)
 	if existing != nil && existing.ID > 0 {
-		return nil, errors.New("email already taken")
+		return nil, ErrEmailTaken
 	}
 
 	// 2. 密码加密
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPwd, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -63,54 +118,170 @@ func (s *UserService) Register(req RegisterDTO) (*domain.User, error) {
 	user := &domain.User{
 		Name:     req.Name,
 		Email:    req.Email,
-		Password: string(hashedPwd),
+		Password: hashedPwd,
 	}
 
-	// 4. 落库
-	if err := s.repo.Create(user); err != nil {
+	// 4. 落库 (并发注册同一邮箱时由唯一索引兜底)
+	if err := s.repo.Create(ctx, user); err != nil {
+		if errors.Is(err, errs.ErrConflict) {
+			return nil, ErrEmailTaken.Wrap(err)
+		}
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (s *UserService) Login(req LoginDTO) (*LoginResponse, error) {
+func (s *UserService) Login(ctx context.Context, req LoginDTO) (*LoginResponse, error) {
+	// 0. 账户或 IP 处于锁定期，直接拒绝，不再计算密码哈希
+	if err := s.throttle.Check(ctx, req.Email, req.IP); err != nil {
+		return nil, err
+	}
+
 	// 1. 查用户
-	user, err := s.repo.FindByEmail(req.Email)
+	user, err := s.repo.FindByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, s.loginFailed(ctx, req.Email, req.IP, ErrInvalidCredentials)
+	}
+
+	// 2. 比对密码 (算法由哈希前缀自动识别)
+	if ok, err := s.hasher.Check(req.Password, user.Password); err != nil || !ok {
+		return nil, s.loginFailed(ctx, req.Email, req.IP, ErrInvalidCredentials) // 模糊报错为了安全
+	}
+
+	// 旧算法或旧参数的哈希趁明文还在手里时升级；失败不影响登录，下次登录再试
+	if s.hasher.NeedsRehash(user.Password) {
+		if rehashed, err := s.hasher.Hash(req.Password); err == nil {
+			user.Password = rehashed
+			if err := s.repo.Update(ctx, user); err != nil {
+				s.logger.Warn("Password rehash failed", "user_id", user.ID, "err", err)
+			}
+		}
+	}
+
+	// 3. 开启了两步验证时返回挑战，失败计数等第二步成功后再清空，否则拿到密码的攻击者可以无限次猜验证码
+	if !user.TwoFactorEnabled() {
+		if err := s.throttle.Reset(ctx, req.Email); err != nil {
+			return nil, err
+		}
+	}
+	return s.LoginUser(ctx, user)
+}
+
+// LoginUser 身份已确认 (密码正确或外部 IdP 登录成功) 后完成登录
+// 开启了两步验证：先不发 Token，返回挑战；否则签发 Token (Access Token + Refresh Token)
+func (s *UserService) LoginUser(ctx context.Context, user *domain.User) (*LoginResponse, error) {
+	if user.TwoFactorEnabled() {
+		mfaToken, err := s.twoFactor.Challenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+	return s.issue(ctx, user)
+}
+
+// LoginTwoFactor 两步登录第二步：挑战 Token + 验证码 (或恢复码) 换取最终 Token
+// 与第一步共用账户/IP 的失败计数和锁定，换新的挑战也不能绕过
+func (s *UserService) LoginTwoFactor(ctx context.Context, req TwoFactorLoginDTO) (*LoginResponse, error) {
+	uid, err := s.twoFactor.ChallengeUserID(ctx, req.MFAToken)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, err
+	}
+	user, err := s.repo.FindByID(ctx, uid)
+	if err != nil {
+		return nil, err
 	}
 
-	// 2. 比对密码
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid credentials") // 模糊报错为了安全
+	// 锁定期间不校验验证码，也就不会消耗恢复码
+	if err := s.throttle.Check(ctx, user.Email, req.IP); err != nil {
+		return nil, err
+	}
+	if _, err := s.twoFactor.VerifyChallenge(ctx, req.MFAToken, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) || errors.Is(err, ErrInvalidMFAToken) {
+			return nil, s.loginFailed(ctx, user.Email, req.IP, err)
+		}
+		return nil, err
 	}
 
-	// 3. 签发 Token
-	// 注意：实际项目中建议在 config.go 中解析成 AuthConfig 结构体
-	// secret := s.config.App.Name + "Secret" // MVP简化，建议用 s.config.Auth.Secret
-	// if val := s.config.Database.DSN; val != "" {
-	// 	// 这里为了演示演示，实际上你应该在 Viper 加载好 Auth 配置
-	// 	// secret = "TempSecretInDev"
-	// }
-	// 使用 .env 中加载的配置 (这里为了不大量改动 config.go，写死示例，请自行优化)
-	jwtSecret := "KeepItSecretKeepItSafe!GoArtisanKey"
+	if err := s.throttle.Reset(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user)
+}
 
-	token, err := auth.GenerateToken(user.ID, jwtSecret, 24*time.Hour)
+func (s *UserService) issue(ctx context.Context, user *domain.User) (*LoginResponse, error) {
+	// 账号密码登录代表用户本人，拥有全部 scope；受限的 scope 由 API Key / OAuth 客户端使用
+	pair, err := s.tokens.Issue(ctx, user.ID, auth.ScopeAll)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
 
 	return &LoginResponse{
-		Token:     token,
-		User:      user,
-		ExpiresIn: 86400,
+		Token:            pair.AccessToken,
+		RefreshToken:     pair.RefreshToken,
+		User:             user,
+		ExpiresIn:        pair.ExpiresIn,
+		RefreshExpiresIn: pair.RefreshExpiresIn,
 	}, nil
 }
 
-func (s *UserService) GetUserProfile(id uint) (*domain.User, error) {
-	ctx := context.Background()
-	cacheKey := fmt.Sprintf("user:profile:%d", id)
+// loginFailed 记录失败并施加渐进延迟；达到阈值时返回锁定错误，否则返回 cause
+func (s *UserService) loginFailed(ctx context.Context, email, ip string, cause error) error {
+	delay, err := s.throttle.RecordFailure(ctx, email, ip)
+	s.throttle.Wait(ctx, delay)
+	if err != nil {
+		return err
+	}
+	return cause
+}
+
+// checkCurrentPassword 敏感操作前确认当前密码；输错按用户计数并施加渐进延迟，达到阈值时锁定
+func checkCurrentPassword(ctx context.Context, throttle *LoginThrottle, hasher hash.Hasher, user *domain.User, password string) error {
+	return throttledReauth(ctx, throttle, user.ID, ErrCurrentPasswordMismatch, func() (bool, error) {
+		ok, err := hasher.Check(password, user.Password)
+		return ok && err == nil, nil
+	})
+}
+
+// throttledReauth 已登录用户再次认证的公共流程：先检查锁定，verify 返回 false 时计数并返回 mismatch
+func throttledReauth(ctx context.Context, throttle *LoginThrottle, userID uint, mismatch error, verify func() (bool, error)) error {
+	if err := throttle.CheckUser(ctx, userID); err != nil {
+		return err
+	}
+	ok, err := verify()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		delay, err := throttle.RecordUserFailure(ctx, userID)
+		throttle.Wait(ctx, delay)
+		if err != nil {
+			return err
+		}
+		return mismatch
+	}
+	return throttle.ResetUser(ctx, userID)
+}
+
+// UnlockLogin 管理员解除账户/IP 的登录锁定
+// 邮箱对应的用户存在时，一并解除确认当前密码 (改密码、注销账户等) 的锁定
+func (s *UserService) UnlockLogin(ctx context.Context, email, ip string) error {
+	var userID uint
+	if email != "" {
+		user, err := s.repo.FindByEmail(ctx, email)
+		switch {
+		case err == nil:
+			userID = user.ID
+		case !errors.Is(err, errs.ErrNotFound): // 不存在的邮箱也可能被锁定，照常清除
+			return err
+		}
+	}
+	return s.throttle.Unlock(ctx, userID, email, ip)
+}
+
+func (s *UserService) GetUserProfile(ctx context.Context, id uint) (*domain.User, error) {
+	cacheKey := profileCacheKey(id)
 
 	// 1. 查缓存
 	val, err := s.redis.Get(ctx, cacheKey).Result()
@@ -122,7 +293,7 @@ func (s *UserService) GetUserProfile(id uint) (*domain.User, error) {
 	}
 
 	// 2. 查数据库 (❌ 不要写 nil，要写真调用)
-	user, err := s.repo.FindByID(id)
+	user, err := s.findUser(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -134,3 +305,113 @@ func (s *UserService) GetUserProfile(id uint) (*domain.User, error) {
 
 	return user, nil
 }
+
+// UpdateProfileDTO 修改资料的输入，字段为空表示不修改
+type UpdateProfileDTO struct {
+	Name            string
+	Email           string
+	CurrentPassword string // 修改邮箱时必填
+}
+
+// UpdateProfile 修改姓名/邮箱；新邮箱需要重新验证
+func (s *UserService) UpdateProfile(ctx context.Context, id uint, req UpdateProfileDTO) (*domain.User, error) {
+	user, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		user.Name = req.Name
+	}
+	if email := normalizeEmail(req.Email); email != "" && email != normalizeEmail(user.Email) {
+		// 邮箱是找回密码的凭据，拿到会话的人不能直接改成自己的邮箱
+		if err := checkCurrentPassword(ctx, s.throttle, s.hasher, user, req.CurrentPassword); err != nil {
+			return nil, err
+		}
+		if existing, _ := s.repo.FindByEmail(ctx, email); existing != nil && existing.ID > 0 {
+			return nil, ErrEmailTaken
+		}
+		user.Email = email
+		user.EmailVerifiedAt = nil
+	}
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		if errors.Is(err, errs.ErrConflict) {
+			return nil, ErrEmailTaken.Wrap(err)
+		}
+		return nil, err
+	}
+	s.redis.Del(ctx, profileCacheKey(user.ID))
+	return user, nil
+}
+
+// ChangePasswordDTO 修改密码的输入
+type ChangePasswordDTO struct {
+	CurrentPassword string
+	Password        string
+}
+
+// ChangePassword 修改密码后其他设备上的会话全部失效，为当前会话签发新 Token
+func (s *UserService) ChangePassword(ctx context.Context, id uint, req ChangePasswordDTO) (*LoginResponse, error) {
+	user, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCurrentPassword(ctx, s.throttle, s.hasher, user, req.CurrentPassword); err != nil {
+		return nil, err
+	}
+	// 绑定阶段拿不到邮箱和姓名，这里补上与个人信息相关的检查
+	if err := s.policy.Check(req.Password, user.Email, user.Name); err != nil {
+		return nil, passwordPolicyError(err)
+	}
+
+	hashed, err := s.hasher.Hash(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	user.Password = hashed
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	s.redis.Del(ctx, profileCacheKey(user.ID))
+
+	if err := s.RevokeCredentials(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user)
+}
+
+// RevokeCredentials 吊销用户已签发的全部凭证：JWT 会话与 API Key
+// 用于 "全部登出"、修改密码、账户被接管等场景，API Key 不随 JWT 代数失效，需要单独删除
+func (s *UserService) RevokeCredentials(ctx context.Context, id uint) error {
+	if err := s.tokens.RevokeAll(ctx, id); err != nil {
+		return err
+	}
+	return s.apiKeys.DeleteByUserID(ctx, id)
+}
+
+// findUser 按 ID 查找用户，不存在时返回 ErrUserNotFound
+func (s *UserService) findUser(ctx context.Context, id uint) (*domain.User, error) {
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return nil, ErrUserNotFound.Wrap(err)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// passwordPolicyError 把违反密码策略的错误转换为 ErrWeakPassword，提示信息为具体规则
+func passwordPolicyError(err error) error {
+	var weak *validator.PasswordPolicyError
+	if errors.As(err, &weak) {
+		return ErrWeakPassword.WithMessage(weak.Message)
+	}
+	return err
+}
+
+// profileCacheKey 用户资料缓存 key，资料变更时需要一并删除
+func profileCacheKey(id uint) string {
+	return fmt.Sprintf("user:profile:%d", id)
+}
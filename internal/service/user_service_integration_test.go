@@ -3,6 +3,7 @@ package service_test
 import (
 	"context"
 	"encoding/json"
+	"log/slog"
 	"testing"
 	"time"
 
@@ -58,10 +59,15 @@ func TestUserService_GetUserProfile_Integration(t *testing.T) {
 	mockRepo := mocks.NewMockUserRepository(ctrl)
 	// Integration Test 中我们通常不 mock config，直接造个结构体
 	mockConfig := &config.Config{
-		App: config.AppConfig{Name: "IntegrationTestApp"},
+		App:  config.AppConfig{Name: "IntegrationTestApp"},
+		Auth: config.AuthConfig{Secret: "integration-secret"},
 	}
 
-	svc := service.NewUserService(mockRepo, mockConfig, realRedis)
+	tokens, err := service.NewTokenService(mockConfig, realRedis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewUserService(mockRepo, nil, mockConfig, realRedis, tokens, service.NewLoginThrottle(mockConfig, realRedis), nil, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
 
 	// 测试数据
 	userID := uint(101)
@@ -78,12 +84,12 @@ func TestUserService_GetUserProfile_Integration(t *testing.T) {
 
 		// Step B: 设置 Mock Repo 期望被调用 (因为缓存没有)
 		mockRepo.EXPECT().
-			FindByID(userID). // 假设你在接口里加了这个方法
+			FindByID(gomock.Any(), userID). // 假设你在接口里加了这个方法
 			Return(expectedUser, nil).
 			Times(1) // 预期只会调用一次 DB
 
 		// C: ⚠️ 必须执行调用，否则 Mock 会报错 Missing Call
-		user, err := svc.GetUserProfile(userID)
+		user, err := svc.GetUserProfile(context.Background(), userID)
 
 		// D: 验证结果
 		assert.NoError(t, err)
@@ -122,7 +128,7 @@ func TestUserService_GetUserProfile_Integration(t *testing.T) {
 		// mockRepo.EXPECT().FindByID... (不需要写！)
 
 		// 3. 调用 Service
-		user, err := svc.GetUserProfile(userID)
+		user, err := svc.GetUserProfile(ctx, userID)
 
 		// 4. 验证
 		assert.NoError(t, err)
@@ -1,14 +1,21 @@
 package service_test
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"testing"
+	"time"
 
 	"go-artisan/internal/config"
 	"go-artisan/internal/domain"
 	"go-artisan/internal/domain/mocks" // 引入刚才生成的 mock 包
 	"go-artisan/internal/service"
+	"go-artisan/pkg/errs"
+	"go-artisan/pkg/hash"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"golang.org/x/crypto/bcrypt"
@@ -23,6 +30,15 @@ func hashPassword(t *testing.T, raw string) string {
 	return string(bytes)
 }
 
+// newHasher 与 hashPassword 一致的 bcrypt 配置，已有哈希不会触发重新哈希
+func newHasher(t *testing.T) hash.Hasher {
+	h, err := hash.New(hash.Config{Driver: hash.DriverBcrypt, BcryptCost: bcrypt.DefaultCost})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
 func TestUserService_Login(t *testing.T) {
 	// 1. 初始化 Mock 控制器
 	ctrl := gomock.NewController(t)
@@ -34,10 +50,21 @@ func TestUserService_Login(t *testing.T) {
 	// 3. 准备配置 (Login 需要读取配置里的 JWT 密钥)
 	mockConfig := &config.Config{
 		App: config.AppConfig{Name: "TestApp"},
+		Auth: config.AuthConfig{
+			Secret:     "test-secret",
+			TTL:        time.Hour,
+			RefreshTTL: 24 * time.Hour,
+			Issuer:     "go-artisan",
+		},
 	}
 
-	// 4. 初始化被测 Service
-	svc := service.NewUserService(mockRepo, mockConfig, nil)
+	// 4. 初始化被测 Service (Refresh Token 需要 Redis，这里用内存版 miniredis)
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(mockConfig, rdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewUserService(mockRepo, nil, mockConfig, rdb, tokens, service.NewLoginThrottle(mockConfig, rdb), nil, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
 
 	// 5. 准备测试数据
 	validEmail := "test@example.com"
@@ -62,7 +89,7 @@ func TestUserService_Login(t *testing.T) {
 				// 期望：Repo.FindByEmail 会被调用一次，参数是 validEmail
 				// 动作：返回一个正常的 User 对象，密码是哈希过的
 				mockRepo.EXPECT().
-					FindByEmail(validEmail).
+					FindByEmail(gomock.Any(), validEmail).
 					Return(&domain.User{
 						ID:       1,
 						Email:    validEmail,
@@ -81,7 +108,7 @@ func TestUserService_Login(t *testing.T) {
 			setupMock: func() {
 				// 模拟数据库找不到用户，返回错误
 				mockRepo.EXPECT().
-					FindByEmail("missing@example.com").
+					FindByEmail(gomock.Any(), "missing@example.com").
 					Return(nil, errors.New("record not found"))
 			},
 			expectError: true, // 应该报错 "invalid credentials"
@@ -96,7 +123,7 @@ func TestUserService_Login(t *testing.T) {
 			setupMock: func() {
 				// 用户找得到，但是密码校验会在 Service 层失败
 				mockRepo.EXPECT().
-					FindByEmail(validEmail).
+					FindByEmail(gomock.Any(), validEmail).
 					Return(&domain.User{
 						ID:       1,
 						Email:    validEmail,
@@ -113,7 +140,7 @@ func TestUserService_Login(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMock() // 设置 Mock 行为
 
-			resp, err := svc.Login(tt.req)
+			resp, err := svc.Login(context.Background(), tt.req)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -123,9 +150,197 @@ func TestUserService_Login(t *testing.T) {
 				assert.NotNil(t, resp)
 				if tt.expectToken {
 					assert.NotEmpty(t, resp.Token)
+					assert.NotEmpty(t, resp.RefreshToken)
 					assert.Equal(t, uint(1), resp.User.ID)
 				}
 			}
 		})
 	}
 }
+
+func TestUserService_Login_Lockout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	mockConfig := &config.Config{
+		Auth: config.AuthConfig{
+			Secret:     "test-secret",
+			TTL:        time.Hour,
+			RefreshTTL: 24 * time.Hour,
+			LoginThrottle: config.LoginThrottleConfig{
+				MaxAccountAttempts: 2,
+				MaxIPAttempts:      10,
+				Window:             time.Minute,
+				LockoutDuration:    time.Minute,
+			},
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(mockConfig, rdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewUserService(mockRepo, nil, mockConfig, rdb, tokens, service.NewLoginThrottle(mockConfig, rdb), nil, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
+
+	email := "victim@example.com"
+	mockRepo.EXPECT().
+		FindByEmail(gomock.Any(), email).
+		Return(&domain.User{ID: 1, Email: email, Password: hashPassword(t, "right-password")}, nil).
+		AnyTimes()
+
+	wrong := service.LoginDTO{Email: email, Password: "guess", IP: "10.0.0.1"}
+	right := service.LoginDTO{Email: email, Password: "right-password", IP: "10.0.0.2"}
+
+	// 第 1 次失败：普通错误
+	_, err = svc.Login(context.Background(), wrong)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, service.ErrAccountLocked)
+
+	// 第 2 次失败：达到阈值，账户锁定
+	_, err = svc.Login(context.Background(), wrong)
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+
+	// 锁定期间即使密码正确 (换了 IP) 也拒绝
+	_, err = svc.Login(context.Background(), right)
+	var lockout *errs.Error
+	if assert.ErrorAs(t, err, &lockout) {
+		assert.ErrorIs(t, err, service.ErrAccountLocked)
+		assert.Positive(t, lockout.RetryAfter)
+	}
+
+	// 管理员解锁后恢复
+	assert.NoError(t, svc.UnlockLogin(context.Background(), email, ""))
+	resp, err := svc.Login(context.Background(), right)
+	assert.NoError(t, err)
+	assert.NotNil(t, resp)
+}
+
+func TestUserService_Login_Rehash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	mockConfig := &config.Config{
+		Auth: config.AuthConfig{Secret: "test-secret", TTL: time.Hour, RefreshTTL: 24 * time.Hour},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(mockConfig, rdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 当前配置为 argon2id，库里还是 bcrypt 哈希
+	argon, err := hash.New(hash.Config{Driver: hash.DriverArgon2id, Argon2: hash.Argon2Params{Memory: 64, Time: 1, Threads: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewUserService(mockRepo, nil, mockConfig, rdb, tokens, service.NewLoginThrottle(mockConfig, rdb), nil, argon, newPolicy(t), slog.New(slog.DiscardHandler))
+
+	user := &domain.User{ID: 1, Email: "old@example.com", Password: hashPassword(t, "secret123")}
+	mockRepo.EXPECT().FindByEmail(gomock.Any(), user.Email).Return(user, nil)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, u *domain.User) error {
+		assert.Equal(t, hash.DriverArgon2id, hash.Identify(u.Password))
+		ok, err := argon.Check("secret123", u.Password)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		return nil
+	})
+
+	_, err = svc.Login(context.Background(), service.LoginDTO{Email: user.Email, Password: "secret123"})
+	assert.NoError(t, err)
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	apiKeys := mocks.NewMockAPIKeyRepository(ctrl)
+	mockConfig := &config.Config{
+		Auth: config.AuthConfig{Secret: "test-secret", TTL: time.Hour, RefreshTTL: 24 * time.Hour},
+	}
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	tokens, err := service.NewTokenService(mockConfig, rdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewUserService(mockRepo, apiKeys, mockConfig, rdb, tokens, service.NewLoginThrottle(mockConfig, rdb), nil, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	user := &domain.User{ID: 1, Name: "alice", Email: "alice@example.com", Password: hashPassword(t, "old-secret")}
+	mockRepo.EXPECT().FindByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+
+	// 资料已被缓存，旧会话仍然有效
+	_, err = svc.GetUserProfile(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, mr.Exists("user:profile:1"))
+	old, err := tokens.Issue(ctx, 1)
+	assert.NoError(t, err)
+
+	// 当前密码错误
+	_, err = svc.ChangePassword(ctx, 1, service.ChangePasswordDTO{CurrentPassword: "wrong", Password: "New-secret-1"})
+	assert.ErrorIs(t, err, service.ErrCurrentPasswordMismatch)
+
+	// 新密码包含邮箱用户名，绑定阶段查不出来，由 Service 拦截
+	_, err = svc.ChangePassword(ctx, 1, service.ChangePasswordDTO{CurrentPassword: "old-secret", Password: "alice-2026"})
+	assert.Error(t, err)
+
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Return(nil)
+	apiKeys.EXPECT().DeleteByUserID(gomock.Any(), uint(1)).Return(nil)
+	res, err := svc.ChangePassword(ctx, 1, service.ChangePasswordDTO{CurrentPassword: "old-secret", Password: "New-secret-1"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res.Token)
+	assert.False(t, mr.Exists("user:profile:1"), "资料缓存应被删除")
+
+	// 其他会话与 API Key 失效，新 Token 可用
+	_, err = tokens.Authenticate(ctx, old.AccessToken)
+	assert.ErrorIs(t, err, service.ErrTokenRevoked)
+	_, err = tokens.Authenticate(ctx, res.Token)
+	assert.NoError(t, err)
+}
+
+func TestUserService_CurrentPasswordLockout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mocks.NewMockUserRepository(ctrl)
+	mockConfig := &config.Config{
+		Auth: config.AuthConfig{
+			Secret:     "test-secret",
+			TTL:        time.Hour,
+			RefreshTTL: 24 * time.Hour,
+			LoginThrottle: config.LoginThrottleConfig{
+				MaxAccountAttempts: 3,
+				MaxIPAttempts:      10,
+				Window:             time.Minute,
+				LockoutDuration:    time.Minute,
+			},
+		},
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	tokens, err := service.NewTokenService(mockConfig, rdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	throttle := service.NewLoginThrottle(mockConfig, rdb)
+	svc := service.NewUserService(mockRepo, nil, mockConfig, rdb, tokens, throttle, nil, newHasher(t), newPolicy(t), slog.New(slog.DiscardHandler))
+	ctx := context.Background()
+
+	user := &domain.User{ID: 1, Name: "alice", Email: "alice@example.com", Password: hashPassword(t, "old-secret")}
+	mockRepo.EXPECT().FindByID(gomock.Any(), uint(1)).Return(user, nil).AnyTimes()
+
+	// 改密码和改邮箱共用同一个计数
+	_, err = svc.ChangePassword(ctx, 1, service.ChangePasswordDTO{CurrentPassword: "guess-1", Password: "New-secret-1"})
+	assert.ErrorIs(t, err, service.ErrCurrentPasswordMismatch)
+	_, err = svc.UpdateProfile(ctx, 1, service.UpdateProfileDTO{Email: "mallory@example.com", CurrentPassword: "guess-2"})
+	assert.ErrorIs(t, err, service.ErrCurrentPasswordMismatch)
+	_, err = svc.ChangePassword(ctx, 1, service.ChangePasswordDTO{CurrentPassword: "guess-3", Password: "New-secret-1"})
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+
+	// 锁定期间即使密码正确也拒绝
+	_, err = svc.ChangePassword(ctx, 1, service.ChangePasswordDTO{CurrentPassword: "old-secret", Password: "New-secret-1"})
+	assert.ErrorIs(t, err, service.ErrAccountLocked)
+
+	// 管理员按邮箱解锁后，当前密码校验恢复 (走到了邮箱占用检查)
+	mockRepo.EXPECT().FindByEmail(gomock.Any(), user.Email).Return(user, nil)
+	assert.NoError(t, svc.UnlockLogin(ctx, user.Email, ""))
+	mockRepo.EXPECT().FindByEmail(gomock.Any(), "taken@example.com").Return(&domain.User{ID: 2}, nil)
+	_, err = svc.UpdateProfile(ctx, 1, service.UpdateProfileDTO{Email: "taken@example.com", CurrentPassword: "old-secret"})
+	assert.ErrorIs(t, err, service.ErrEmailTaken)
+}
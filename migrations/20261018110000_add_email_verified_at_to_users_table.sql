@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP NULL DEFAULT NULL AFTER password;
+-- 上线前已注册的用户视为已验证，避免被 verified 中间件拦截
+UPDATE users SET email_verified_at = created_at;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN email_verified_at;
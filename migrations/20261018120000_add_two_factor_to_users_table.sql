@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN two_factor_secret VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN two_factor_confirmed_at TIMESTAMP NULL DEFAULT NULL;
+
+CREATE TABLE two_factor_recovery_codes (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP NULL DEFAULT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_two_factor_recovery_codes_user_id (user_id, code_hash),
+    CONSTRAINT fk_two_factor_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE two_factor_recovery_codes;
+ALTER TABLE users
+    DROP COLUMN two_factor_secret,
+    DROP COLUMN two_factor_confirmed_at;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE oauth_clients (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    client_id VARCHAR(64) NOT NULL UNIQUE,
+    secret_hash CHAR(64) NULL DEFAULT NULL,
+    name VARCHAR(100) NOT NULL,
+    redirect_uris JSON NULL,
+    grant_types JSON NULL,
+    scopes JSON NULL,
+    confidential TINYINT(1) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE oauth_clients;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- 软删除：注销的账户在保留期内可由管理员恢复，期满后由 artisan users:purge 清除
+-- 保留期内邮箱仍占用唯一索引，同一邮箱不能重新注册
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP NULL DEFAULT NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+
+-- +goose Down
+DROP INDEX idx_users_deleted_at ON users;
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- 清除时仍被其他表引用的用户只做匿名化，标记后不会再被清除或恢复
+ALTER TABLE users ADD COLUMN anonymized_at TIMESTAMP NULL DEFAULT NULL;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN anonymized_at;
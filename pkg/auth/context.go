@@ -0,0 +1,51 @@
+package auth
+
+import "github.com/gin-gonic/gin"
+
+// 认证中间件写入 gin.Context 的键
+const (
+	ContextUserIDKey = "userID"
+	ContextClaimsKey = "claims" // *Claims，登出等操作需要 jti/sid (仅 JWT 登录时存在)
+	ContextScopesKey = "scopes" // []string，JWT 与 API Key 统一写入，供 RequireScope 使用
+)
+
+// Principal 当前请求已认证的调用者
+type Principal struct {
+	UserID uint
+	Scopes []string
+	Claims *Claims // 仅 JWT 登录时存在，API Key 认证时为 nil
+}
+
+// SetCurrentUser 由认证中间件调用，写入当前调用者
+func SetCurrentUser(c *gin.Context, p Principal) {
+	c.Set(ContextUserIDKey, p.UserID)
+	c.Set(ContextScopesKey, p.Scopes)
+	if p.Claims != nil {
+		c.Set(ContextClaimsKey, p.Claims)
+	}
+}
+
+// CurrentUser 取出当前调用者，路由没有挂认证中间件时返回 false
+func CurrentUser(c *gin.Context) (Principal, bool) {
+	uid, ok := c.Get(ContextUserIDKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p := Principal{UserID: uid.(uint)}
+	if scopes, ok := c.Get(ContextScopesKey); ok {
+		p.Scopes, _ = scopes.([]string)
+	}
+	if claims, ok := c.Get(ContextClaimsKey); ok {
+		p.Claims, _ = claims.(*Claims)
+	}
+	return p, true
+}
+
+// MustCurrentUser 只能用在挂了认证中间件的路由上，否则 panic (由 Recovery 转成 500)
+func MustCurrentUser(c *gin.Context) Principal {
+	p, ok := CurrentUser(c)
+	if !ok {
+		panic("auth: no authenticated user in context, is the route missing AuthMiddleware?")
+	}
+	return p
+}
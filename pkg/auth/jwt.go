@@ -5,34 +5,110 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 // Claims 自定义载荷
 type Claims struct {
-	UserID uint `json:"user_id"`
+	UserID     uint             `json:"user_id"`
+	SessionID  string           `json:"sid,omitempty"`       // 所属登录会话 (Refresh Token family)
+	Generation int64            `json:"gen"`                 // 用户 Token 代数，"全部登出" 时递增使旧 Token 失效
+	Scope      string           `json:"scope,omitempty"`     // 空格分隔的权限范围 (RFC 9068)；为空时第一方 Token 拥有全部权限，OAuth2 客户端 Token 没有任何 scope，见 Scopes
+	ClientID   string           `json:"client_id,omitempty"` // 通过 OAuth2 签发时的客户端，第一方登录为空
+	AuthTime   *jwt.NumericDate `json:"auth_time,omitempty"` // 用户实际输入凭证登录的时间，轮换 Token 时保持不变
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成 Token
-func GenerateToken(userID uint, secret string, ttl time.Duration) (string, error) {
+// Scopes 解析后的 scope 列表
+// 引入 scope 之前签发的第一方 Token 没有这个声明，按 ScopeAll 处理 (现在第一方登录总是写入 "*")
+func (c *Claims) Scopes() []string {
+	if c.Scope == "" && c.ClientID == "" {
+		return []string{ScopeAll}
+	}
+	return ParseScope(c.Scope)
+}
+
+// Options 签发与校验 Token 所需的参数
+type Options struct {
+	Keys      *KeySet // 签名算法与密钥 (支持 HS256/RS256/ES256/EdDSA 及轮换)
+	TTL       time.Duration
+	Issuer    string
+	Audience  string        // 为空时不写入也不校验 aud
+	ClockSkew time.Duration // 校验 exp/nbf/iat 时允许的时钟偏差
+}
+
+// ClaimOption 签发时追加的可选载荷
+type ClaimOption func(*Claims)
+
+// WithSession 绑定登录会话 ID
+func WithSession(sessionID string) ClaimOption {
+	return func(c *Claims) { c.SessionID = sessionID }
+}
+
+// WithGeneration 写入用户当前的 Token 代数
+func WithGeneration(gen int64) ClaimOption {
+	return func(c *Claims) { c.Generation = gen }
+}
+
+// WithScopes 写入权限范围
+func WithScopes(scopes ...string) ClaimOption {
+	return func(c *Claims) { c.Scope = JoinScope(scopes) }
+}
+
+// WithAuthTime 写入登录时间
+func WithAuthTime(t time.Time) ClaimOption {
+	return func(c *Claims) { c.AuthTime = jwt.NewNumericDate(t) }
+}
+
+// WithClient 绑定签发 Token 的 OAuth2 客户端
+func WithClient(clientID string) ClaimOption {
+	return func(c *Claims) { c.ClientID = clientID }
+}
+
+// GenerateToken 生成 Token，每个 Token 都带唯一 jti 以便单独吊销
+func GenerateToken(userID uint, opts Options, claimOpts ...ClaimOption) (string, error) {
+	now := time.Now()
 	claims := Claims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "go-artisan",
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(opts.TTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    opts.Issuer,
 		},
 	}
+	if opts.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{opts.Audience}
+	}
+	for _, opt := range claimOpts {
+		opt(&claims)
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	if opts.Keys == nil {
+		return "", errors.New("auth: no signing key configured")
+	}
+	return opts.Keys.sign(claims)
 }
 
 // ParseToken 解析 Token
-func ParseToken(tokenString string, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+func ParseToken(tokenString string, opts Options) (*Claims, error) {
+	if opts.Keys == nil {
+		return nil, errors.New("auth: no verification key configured")
+	}
+	parserOpts := []jwt.ParserOption{
+		// 只接受 KeySet 中存在的算法，防止 alg=none 或算法混淆攻击
+		jwt.WithValidMethods(opts.Keys.methods()),
+		jwt.WithLeeway(opts.ClockSkew),
+		jwt.WithExpirationRequired(),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, opts.Keys.keyfunc, parserOpts...)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 支持的签名算法
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// KeySetConfig 描述如何构建 KeySet
+type KeySetConfig struct {
+	Algorithm      string   // 为空时默认 HS256
+	Secret         string   // HS256 使用的共享密钥
+	PrivateKeyFile string   // 非对称算法的当前签名私钥 (PEM)
+	PublicKeyFiles []string // 轮换期间仍需接受的旧公钥 (PEM)，可以是不同算法
+}
+
+// Key 一把签名/验签密钥
+type Key struct {
+	ID     string // kid，非对称密钥取 RFC 7638 指纹，轮换前后保持稳定
+	Method jwt.SigningMethod
+	sign   any // 签名密钥：HMAC 为 []byte，其余为私钥；仅用于验签的旧密钥为 nil
+	verify any // 验签密钥：HMAC 为 []byte，其余为公钥
+}
+
+// KeySet 当前签名密钥 + 所有可接受的验签密钥
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewHMACKeySet 使用共享密钥 (HS256) 的 KeySet
+func NewHMACKeySet(secret string) (*KeySet, error) {
+	if secret == "" {
+		return nil, errors.New("auth: HS256 requires a non-empty secret")
+	}
+	key := &Key{Method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+	return &KeySet{signing: key, keys: map[string]*Key{"": key}}, nil
+}
+
+// LoadKeySet 按配置从 PEM 文件加载密钥
+func LoadKeySet(cfg KeySetConfig) (*KeySet, error) {
+	alg := cfg.Algorithm
+	if alg == "" {
+		alg = AlgHS256
+	}
+	if alg == AlgHS256 {
+		return NewHMACKeySet(cfg.Secret)
+	}
+
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("auth: %s requires a private key file", alg)
+	}
+	pem, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("auth: read private key: %w", err)
+	}
+	signing, err := ParsePrivateKey(alg, pem)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{signing: signing, keys: map[string]*Key{signing.ID: signing}}
+	for _, file := range cfg.PublicKeyFiles {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("auth: read public key %s: %w", file, err)
+		}
+		key, err := ParsePublicKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("auth: parse public key %s: %w", file, err)
+		}
+		ks.AddVerificationKey(key)
+	}
+	return ks, nil
+}
+
+// ParsePrivateKey 解析 PEM 私钥，算法必须与密钥类型匹配
+func ParsePrivateKey(alg string, pem []byte) (*Key, error) {
+	var (
+		priv crypto.Signer
+		err  error
+	)
+	switch alg {
+	case AlgRS256:
+		priv, err = jwt.ParseRSAPrivateKeyFromPEM(pem)
+	case AlgES256:
+		var ec *ecdsa.PrivateKey
+		ec, err = jwt.ParseECPrivateKeyFromPEM(pem)
+		if err == nil && ec.Curve != elliptic.P256() {
+			err = errors.New("ES256 requires a P-256 key")
+		}
+		priv = ec
+	case AlgEdDSA:
+		var k crypto.PrivateKey
+		k, err = jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err == nil {
+			priv, _ = k.(crypto.Signer)
+		}
+	default:
+		return nil, fmt.Errorf("auth: unsupported algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("auth: parse %s private key: %w", alg, err)
+	}
+
+	key, err := newPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.sign = priv
+	return key, nil
+}
+
+// ParsePublicKey 解析 PEM 公钥，算法由密钥类型推断
+func ParsePublicKey(pem []byte) (*Key, error) {
+	if pub, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+		return newPublicKey(pub)
+	}
+	if pub, err := jwt.ParseECPublicKeyFromPEM(pem); err == nil {
+		return newPublicKey(pub)
+	}
+	if pub, err := jwt.ParseEdPublicKeyFromPEM(pem); err == nil {
+		return newPublicKey(pub)
+	}
+	return nil, errors.New("auth: unsupported or invalid public key")
+}
+
+func newPublicKey(pub crypto.PublicKey) (*Key, error) {
+	key := &Key{verify: pub}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New("auth: only P-256 EC keys are supported")
+		}
+		key.Method = jwt.SigningMethodES256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("auth: unsupported public key type %T", pub)
+	}
+
+	jwk, err := publicJWK(key)
+	if err != nil {
+		return nil, err
+	}
+	key.ID = jwk.thumbprint()
+	return key, nil
+}
+
+// AddVerificationKey 追加一把只用于验签的密钥 (密钥轮换时保留旧公钥)
+func (ks *KeySet) AddVerificationKey(key *Key) {
+	ks.keys[key.ID] = &Key{ID: key.ID, Method: key.Method, verify: key.verify}
+}
+
+// SigningKey 当前用于签名的密钥
+func (ks *KeySet) SigningKey() *Key {
+	return ks.signing
+}
+
+// sign 用当前密钥签名，非对称算法写入 kid 头
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	if ks.signing.ID != "" {
+		token.Header["kid"] = ks.signing.ID
+	}
+	return token.SignedString(ks.signing.sign)
+}
+
+// keyfunc 按 kid 选出验签密钥，并确保 alg 与该密钥匹配
+func (ks *KeySet) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("auth: algorithm %s does not match key %q", token.Method.Alg(), kid)
+	}
+	return key.verify, nil
+}
+
+// methods 所有可接受的算法，交给 jwt.WithValidMethods 防止 alg=none 或算法混淆
+func (ks *KeySet) methods() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, key := range ks.keys {
+		if alg := key.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
@@ -0,0 +1,152 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-artisan/pkg/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair 生成密钥并写成 PEM 文件，返回 (私钥路径, 公钥路径)
+func writeKeyPair(t *testing.T, priv any, pub any) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	privPath := filepath.Join(dir, "private.pem")
+	pubPath := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600))
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o600))
+	return privPath, pubPath
+}
+
+func options(keys *auth.KeySet) auth.Options {
+	return auth.Options{Keys: keys, TTL: time.Minute, Issuer: "go-artisan"}
+}
+
+func TestKeySet_SignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	cases := []struct {
+		alg  string
+		priv any
+		pub  any
+		kty  string
+	}{
+		{auth.AlgRS256, rsaKey, &rsaKey.PublicKey, "RSA"},
+		{auth.AlgES256, ecKey, &ecKey.PublicKey, "EC"},
+		{auth.AlgEdDSA, edPriv, edPub, "OKP"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.alg, func(t *testing.T) {
+			privPath, _ := writeKeyPair(t, tc.priv, tc.pub)
+			keys, err := auth.LoadKeySet(auth.KeySetConfig{Algorithm: tc.alg, PrivateKeyFile: privPath})
+			require.NoError(t, err)
+
+			token, err := auth.GenerateToken(42, options(keys))
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &auth.Claims{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.alg, parsed.Method.Alg())
+			assert.Equal(t, keys.SigningKey().ID, parsed.Header["kid"])
+
+			claims, err := auth.ParseToken(token, options(keys))
+			require.NoError(t, err)
+			assert.Equal(t, uint(42), claims.UserID)
+
+			jwks := keys.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, tc.kty, jwks.Keys[0].Kty)
+			assert.Equal(t, keys.SigningKey().ID, jwks.Keys[0].Kid)
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	oldPriv, oldPub := writeKeyPair(t, oldKey, &oldKey.PublicKey)
+	newPriv, _ := writeKeyPair(t, newKey, &newKey.PublicKey)
+
+	oldKeys, err := auth.LoadKeySet(auth.KeySetConfig{Algorithm: auth.AlgRS256, PrivateKeyFile: oldPriv})
+	require.NoError(t, err)
+	oldToken, err := auth.GenerateToken(1, options(oldKeys))
+	require.NoError(t, err)
+
+	// 轮换：用新私钥签名，同时保留旧公钥用于验签
+	rotated, err := auth.LoadKeySet(auth.KeySetConfig{
+		Algorithm:      auth.AlgES256,
+		PrivateKeyFile: newPriv,
+		PublicKeyFiles: []string{oldPub},
+	})
+	require.NoError(t, err)
+
+	_, err = auth.ParseToken(oldToken, options(rotated))
+	assert.NoError(t, err, "轮换期间旧 Token 仍然有效")
+	assert.Len(t, rotated.JWKS().Keys, 2)
+
+	// 旧公钥下线后，旧 Token 被拒绝
+	retired, err := auth.LoadKeySet(auth.KeySetConfig{Algorithm: auth.AlgES256, PrivateKeyFile: newPriv})
+	require.NoError(t, err)
+	_, err = auth.ParseToken(oldToken, options(retired))
+	assert.Error(t, err)
+}
+
+func TestKeySet_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privPath, pubPath := writeKeyPair(t, rsaKey, &rsaKey.PublicKey)
+
+	keys, err := auth.LoadKeySet(auth.KeySetConfig{Algorithm: auth.AlgRS256, PrivateKeyFile: privPath})
+	require.NoError(t, err)
+
+	// 攻击者拿公钥当 HMAC 密钥伪造 Token
+	pubPEM, err := os.ReadFile(pubPath)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			Issuer:    "go-artisan",
+		},
+	})
+	forged.Header["kid"] = keys.SigningKey().ID
+	token, err := forged.SignedString(pubPEM)
+	require.NoError(t, err)
+
+	_, err = auth.ParseToken(token, options(keys))
+	assert.Error(t, err)
+}
+
+func TestKeySet_HMACIsNeverPublished(t *testing.T) {
+	keys, err := auth.NewHMACKeySet("shared-secret")
+	require.NoError(t, err)
+	assert.Empty(t, keys.JWKS().Keys)
+}
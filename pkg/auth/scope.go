@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ScopeAll 拥有全部权限，用户通过账号密码登录得到的 Token 使用它
+const ScopeAll = "*"
+
+// scopePattern 资源:动作，如 orders:read、orders:*；也允许单段如 admin
+var scopePattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]*(:([a-z0-9_.-]+|\*))?$`)
+
+// ValidScope 校验 scope 格式
+func ValidScope(scope string) bool {
+	return scope == ScopeAll || scopePattern.MatchString(scope)
+}
+
+// ParseScope 解析 OAuth2 风格的空格分隔 scope 字符串
+func ParseScope(s string) []string {
+	return strings.Fields(s)
+}
+
+// JoinScope 拼成空格分隔的 scope 字符串
+func JoinScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// HasScope granted 是否满足 required
+//
+//	"*"          满足任何 scope
+//	"orders:*"   满足 orders:read、orders:write 等
+//	"orders"     满足 orders:read 等 (资源级授权)
+func HasScope(granted []string, required string) bool {
+	resource, _, _ := strings.Cut(required, ":")
+	for _, g := range granted {
+		if g == ScopeAll || g == required || g == resource || g == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingScopes 返回 granted 中缺少的 required scope
+func MissingScopes(granted []string, required ...string) []string {
+	var missing []string
+	for _, r := range required {
+		if !HasScope(granted, r) {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
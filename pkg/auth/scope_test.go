@@ -0,0 +1,47 @@
+package auth_test
+
+import (
+	"testing"
+
+	"go-artisan/pkg/auth"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		granted  []string
+		required string
+		want     bool
+	}{
+		{[]string{"*"}, "orders:write", true},
+		{[]string{"orders:read"}, "orders:read", true},
+		{[]string{"orders:read"}, "orders:write", false},
+		{[]string{"orders:*"}, "orders:write", true},
+		{[]string{"orders"}, "orders:write", true},
+		{[]string{"users:*"}, "orders:read", false},
+		{nil, "orders:read", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, auth.HasScope(tt.granted, tt.required), "%v -> %s", tt.granted, tt.required)
+	}
+
+	assert.Equal(t, []string{"admin"}, auth.MissingScopes([]string{"orders:read"}, "orders:read", "admin"))
+}
+
+func TestValidScope(t *testing.T) {
+	for _, s := range []string{"*", "admin", "orders:read", "orders:*", "billing.invoices:write"} {
+		assert.True(t, auth.ValidScope(s), s)
+	}
+	for _, s := range []string{"", "Orders:read", "orders:", ":read", "orders read", "orders:read:extra"} {
+		assert.False(t, auth.ValidScope(s), s)
+	}
+}
+
+func TestClaims_Scopes_Legacy(t *testing.T) {
+	// 引入 scope 之前签发的第一方 Token
+	assert.Equal(t, []string{auth.ScopeAll}, (&auth.Claims{}).Scopes())
+	// OAuth2 客户端的 Token 没有 scope 就是没有权限
+	assert.Empty(t, (&auth.Claims{ClientID: "client"}).Scopes())
+	assert.Equal(t, []string{"orders:read"}, (&auth.Claims{Scope: "orders:read"}).Scopes())
+}
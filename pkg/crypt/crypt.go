@@ -0,0 +1,56 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var ErrDecrypt = errors.New("crypt: unable to decrypt payload")
+
+// Encrypter 对称加密 (类似 Laravel 的 Crypt facade)，用于需要还原明文的敏感字段，如 TOTP 密钥
+// 使用 AES-256-GCM，密钥由 APP_KEY 经 SHA-256 派生；更换 APP_KEY 后旧数据将无法解密
+type Encrypter struct {
+	aead cipher.AEAD
+}
+
+func New(key string) (*Encrypter, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypter{aead: aead}, nil
+}
+
+// Encrypt 返回 base64(nonce || ciphertext)
+func (e *Encrypter) Encrypt(plain string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("crypt: generate nonce: %w", err)
+	}
+	sealed := e.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的输出，数据被篡改或密钥不对时返回 ErrDecrypt
+func (e *Encrypter) Decrypt(payload string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(raw) < e.aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, sealed := raw[:e.aead.NonceSize()], raw[e.aead.NonceSize():]
+	plain, err := e.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
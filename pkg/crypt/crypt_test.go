@@ -0,0 +1,56 @@
+package crypt_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"go-artisan/pkg/crypt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncrypter_RoundTrip(t *testing.T) {
+	e, err := crypt.New("test-app-key")
+	require.NoError(t, err)
+
+	payload, err := e.Encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.NotContains(t, payload, "JBSWY3DPEHPK3PXP")
+
+	plain, err := e.Decrypt(payload)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plain)
+
+	// 每次加密使用随机 nonce，相同明文得到不同密文
+	again, err := e.Encrypt("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.NotEqual(t, payload, again)
+}
+
+func TestEncrypter_Decrypt_Rejects(t *testing.T) {
+	e, err := crypt.New("test-app-key")
+	require.NoError(t, err)
+	payload, err := e.Encrypt("secret")
+	require.NoError(t, err)
+
+	raw, err := base64.StdEncoding.DecodeString(payload)
+	require.NoError(t, err)
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-1] ^= 0x01
+
+	other, err := crypt.New("another-app-key")
+	require.NoError(t, err)
+	_, err = other.Decrypt(payload)
+	assert.ErrorIs(t, err, crypt.ErrDecrypt, "different key")
+
+	for name, p := range map[string]string{
+		"tampered":   base64.StdEncoding.EncodeToString(tampered),
+		"truncated":  base64.StdEncoding.EncodeToString(raw[:8]),
+		"not base64": "%%%",
+		"empty":      "",
+	} {
+		_, err := e.Decrypt(p)
+		assert.ErrorIs(t, err, crypt.ErrDecrypt, name)
+	}
+}
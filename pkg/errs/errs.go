@@ -0,0 +1,165 @@
+// Package errs 带 HTTP 语义和业务码的错误类型
+//
+// Service 返回 *Error，Handler 交给 c.Error(err)，由 ErrorHandler 中间件统一渲染；
+// 其他错误一律视为内部错误，返回 500 且不暴露细节
+//
+// 业务码约定为 HTTP 状态码 * 100 + 序号 (如 40901 邮箱已被占用)，一经发布不再修改，
+// 客户端应根据业务码而不是 message 文案做判断
+package errs
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Kind 错误类别，决定 HTTP 状态码
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindUnauthorized
+	KindForbidden
+	KindValidation
+	KindRateLimited
+)
+
+// CodeInternal 内部错误的业务码
+const CodeInternal = 50000
+
+// HTTPStatus 类别对应的 HTTP 状态码
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindValidation:
+		return http.StatusUnprocessableEntity
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// 各类别的通用错误 (业务码为 0)，errors.Is 可匹配该类别的任意错误:
+//
+//	errors.Is(ErrEmailTaken, errs.ErrConflict) // true
+var (
+	ErrNotFound     = &Error{Kind: KindNotFound, Message: "resource not found"}
+	ErrConflict     = &Error{Kind: KindConflict, Message: "resource already exists"}
+	ErrUnauthorized = &Error{Kind: KindUnauthorized, Message: "unauthenticated"}
+	ErrForbidden    = &Error{Kind: KindForbidden, Message: "forbidden"}
+	ErrValidation   = &Error{Kind: KindValidation, Message: "validation failed"}
+	ErrRateLimited  = &Error{Kind: KindRateLimited, Message: "too many requests"}
+)
+
+// Error 带类别和业务码的错误，作为包级变量定义后不要修改，需要附加信息时用 Wrap / WithField 等生成副本
+type Error struct {
+	Kind       Kind
+	Code       int           // 业务码，为 0 时使用 HTTP 状态码 * 100
+	Message    string        // 返回给客户端的提示
+	Field      string        // 出错的请求字段，渲染为 {"<field>": "<message>"}
+	RetryAfter time.Duration // RateLimited 时告诉客户端多久后重试
+
+	cause error // 底层错误，只用于日志，不返回给客户端
+}
+
+func NotFound(code int, msg string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: msg}
+}
+
+func Conflict(code int, msg string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: msg}
+}
+
+func Unauthorized(code int, msg string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: msg}
+}
+
+func Forbidden(code int, msg string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: msg}
+}
+
+func Validation(code int, msg string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: msg}
+}
+
+func RateLimited(code int, msg string) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: msg}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is 业务码相同即视为同一错误；目标业务码为 0 (通用错误) 时只比较类别
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.Code == 0 {
+		return t.Kind == e.Kind
+	}
+	return t.Code == e.Code
+}
+
+// BusinessCode 渲染给客户端的业务码
+func (e *Error) BusinessCode() int {
+	if e.Code != 0 {
+		return e.Code
+	}
+	return e.Kind.HTTPStatus() * 100
+}
+
+// Wrap 返回附带底层错误的副本
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// WithField 返回指向某个请求字段的副本
+func (e *Error) WithField(field string) *Error {
+	c := *e
+	c.Field = field
+	return &c
+}
+
+// WithMessage 返回替换了提示信息的副本 (业务码不变)
+func (e *Error) WithMessage(msg string) *Error {
+	c := *e
+	c.Message = msg
+	return &c
+}
+
+// RetryIn 返回带重试时间的副本
+func (e *Error) RetryIn(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = d
+	return &c
+}
+
+// KindOf 错误链中第一个 *Error 的类别，没有时为 KindInternal
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
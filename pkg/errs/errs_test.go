@@ -0,0 +1,35 @@
+package errs_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"go-artisan/pkg/errs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_Is(t *testing.T) {
+	errEmailTaken := errs.Conflict(40901, "email already taken")
+	cause := errors.New("Error 1062 (23000): Duplicate entry")
+
+	err := fmt.Errorf("register: %w", errEmailTaken.Wrap(cause).WithField("email"))
+
+	assert.ErrorIs(t, err, errEmailTaken, "副本的业务码不变")
+	assert.ErrorIs(t, err, errs.ErrConflict, "通用错误按类别匹配")
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, errs.ErrNotFound)
+	assert.NotErrorIs(t, err, errs.Conflict(40902, "other"))
+
+	assert.Equal(t, errs.KindConflict, errs.KindOf(err))
+	assert.Equal(t, errs.KindInternal, errs.KindOf(cause))
+}
+
+func TestError_BusinessCode(t *testing.T) {
+	assert.Equal(t, 40400, errs.ErrNotFound.BusinessCode())
+	assert.Equal(t, http.StatusNotFound, errs.ErrNotFound.Kind.HTTPStatus())
+	assert.Equal(t, 42201, errs.Validation(42201, "bad").BusinessCode())
+	assert.Equal(t, http.StatusTooManyRequests, errs.ErrRateLimited.Kind.HTTPStatus())
+}
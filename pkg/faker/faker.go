@@ -0,0 +1,137 @@
+// Package faker 生成看起来真实的假数据，供模型工厂和测试使用
+//
+//	f := faker.New()
+//	f.Name()      // "Olivia Martin"
+//	f.Email()     // "olivia.martin.4f9c21@example.com"
+//	f.Sentence(6) // "Quia dolor sit amet porro velit."
+//
+// 同一个种子生成的序列相同，测试需要可复现的数据时使用 NewSeeded
+package faker
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	firstNames = []string{
+		"Olivia", "Liam", "Emma", "Noah", "Amelia", "Oliver", "Sophia", "Elijah", "Mia", "James",
+		"Charlotte", "William", "Ava", "Benjamin", "Isabella", "Lucas", "Harper", "Henry", "Evelyn", "Theodore",
+		"Wei", "Fang", "Hiroshi", "Yuki", "Ananya", "Arjun", "Sofia", "Mateo", "Chloe", "Leo",
+	}
+	lastNames = []string{
+		"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Martin", "Lopez",
+		"Wilson", "Anderson", "Taylor", "Thomas", "Moore", "Jackson", "White", "Harris", "Clark", "Lewis",
+		"Wang", "Li", "Zhang", "Chen", "Tanaka", "Sato", "Kim", "Patel", "Singh", "Rossi",
+	}
+	words = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor
+		incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco
+		laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit esse
+		cillum fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui officia
+		deserunt mollit anim id est laborum`)
+	emailDomains = []string{"example.com", "example.org", "example.net"}
+)
+
+// Faker 假数据生成器，非并发安全，每个 goroutine 使用各自的实例
+type Faker struct {
+	r *rand.Rand
+}
+
+// New 随机种子
+func New() *Faker {
+	return &Faker{r: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+}
+
+// NewSeeded 固定种子，生成可复现的序列
+func NewSeeded(seed uint64) *Faker {
+	return &Faker{r: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// Int 返回 [min, max] 之间的整数
+func (f *Faker) Int(min, max int) int {
+	if max <= min {
+		return min
+	}
+	return min + f.r.IntN(max-min+1)
+}
+
+// Bool 以 chance (0~1) 的概率返回 true
+func (f *Faker) Bool(chance float64) bool {
+	return f.r.Float64() < chance
+}
+
+// Pick 从 items 中随机取一个
+func Pick[T any](f *Faker, items []T) T {
+	return items[f.r.IntN(len(items))]
+}
+
+func (f *Faker) FirstName() string {
+	return Pick(f, firstNames)
+}
+
+func (f *Faker) LastName() string {
+	return Pick(f, lastNames)
+}
+
+func (f *Faker) Name() string {
+	return f.FirstName() + " " + f.LastName()
+}
+
+// Email 由姓名和随机后缀组成，多次生成基本不会重复，适合有唯一索引的列
+func (f *Faker) Email() string {
+	return fmt.Sprintf("%s.%s.%s@%s",
+		strings.ToLower(f.FirstName()), strings.ToLower(f.LastName()), f.Hex(6), Pick(f, emailDomains))
+}
+
+// Hex 长度为 n 的随机十六进制字符串
+func (f *Faker) Hex(n int) string {
+	b := make([]byte, (n+1)/2)
+	for i := range b {
+		b[i] = byte(f.r.UintN(256))
+	}
+	return hex.EncodeToString(b)[:n]
+}
+
+func (f *Faker) Word() string {
+	return Pick(f, words)
+}
+
+// Sentence n 个单词组成的句子，首字母大写并以句号结尾
+func (f *Faker) Sentence(n int) string {
+	ws := make([]string, n)
+	for i := range ws {
+		ws[i] = f.Word()
+	}
+	s := []rune(strings.Join(ws, " "))
+	if len(s) > 0 {
+		s[0] = unicode.ToUpper(s[0])
+	}
+	return string(s) + "."
+}
+
+// Paragraph n 个句子组成的段落
+func (f *Faker) Paragraph(n int) string {
+	ss := make([]string, n)
+	for i := range ss {
+		ss[i] = f.Sentence(f.Int(4, 12))
+	}
+	return strings.Join(ss, " ")
+}
+
+// TimeBetween [from, to) 之间的随机时间
+func (f *Faker) TimeBetween(from, to time.Time) time.Time {
+	if !to.After(from) {
+		return from
+	}
+	return from.Add(time.Duration(f.r.Int64N(int64(to.Sub(from)))))
+}
+
+// PastTime 过去 d 时间内的随机时间
+func (f *Faker) PastTime(d time.Duration) time.Time {
+	now := time.Now()
+	return f.TimeBetween(now.Add(-d), now)
+}
@@ -0,0 +1,122 @@
+package hash
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2Params argon2id 参数，默认值取自 OWASP 推荐 (m=19 MiB, t=2, p=1)
+type Argon2Params struct {
+	Memory     uint32 // KiB
+	Time       uint32 // 迭代次数
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+func (p Argon2Params) withDefaults() Argon2Params {
+	if p.Memory == 0 {
+		p.Memory = 19 * 1024
+	}
+	if p.Time == 0 {
+		p.Time = 2
+	}
+	if p.Threads == 0 {
+		p.Threads = 1
+	}
+	if p.SaltLength == 0 {
+		p.SaltLength = 16
+	}
+	if p.KeyLength == 0 {
+		p.KeyLength = 32
+	}
+	return p
+}
+
+// Argon2id argon2id 实现，输出 PHC 格式:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<base64 salt>$<base64 hash>
+type Argon2id struct {
+	params Argon2Params
+}
+
+func NewArgon2id(params Argon2Params) (*Argon2id, error) {
+	return &Argon2id{params: params.withDefaults()}, nil
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	p := a.params
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("hash: generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Check(password, hashed string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return false, err
+	}
+	// 用哈希里记录的参数计算，参数调整后旧哈希依然能校验
+	other := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (a *Argon2id) NeedsRehash(hashed string) bool {
+	p, salt, key, err := decodeArgon2id(hashed)
+	if err != nil {
+		return true
+	}
+	return p.Memory != a.params.Memory ||
+		p.Time != a.params.Time ||
+		p.Threads != a.params.Threads ||
+		uint32(len(salt)) != a.params.SaltLength ||
+		uint32(len(key)) != a.params.KeyLength
+}
+
+var errInvalidArgon2 = errors.New("hash: invalid argon2id hash")
+
+func decodeArgon2id(hashed string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 || parts[1] != DriverArgon2id {
+		return p, nil, nil, errInvalidArgon2
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, errInvalidArgon2
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, errInvalidArgon2
+	}
+	// 参数来自数据库，不可信：argon2.IDKey 在 p = 0 时会 panic，m / t 为 0 也不是合法哈希
+	if p.Memory == 0 || p.Time == 0 || p.Threads == 0 {
+		return p, nil, nil, errInvalidArgon2
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, errInvalidArgon2
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, errInvalidArgon2
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,96 @@
+package hash
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 支持的算法
+const (
+	DriverBcrypt   = "bcrypt"
+	DriverArgon2id = "argon2id"
+)
+
+var ErrUnknownHash = errors.New("hash: unknown hash format")
+
+// Hasher 密码哈希 (类似 Laravel 的 Hash facade)
+type Hasher interface {
+	// Hash 生成 PHC 格式的哈希，如 $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+	Hash(password string) (string, error)
+	// Check 校验密码，算法由哈希前缀自动识别
+	Check(password, hashed string) (bool, error)
+	// NeedsRehash 哈希使用的算法或参数与当前配置不一致时返回 true
+	NeedsRehash(hashed string) bool
+}
+
+// Config 哈希配置
+type Config struct {
+	Driver     string // bcrypt / argon2id
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// Manager 按配置的 driver 生成新哈希，同时能校验所有已支持算法的旧哈希，
+// 方便在用户登录时把旧哈希平滑迁移到新算法
+type Manager struct {
+	driver  string
+	hashers map[string]Hasher
+}
+
+var _ Hasher = (*Manager)(nil)
+
+func New(cfg Config) (*Manager, error) {
+	bcryptHasher, err := NewBcrypt(cfg.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+	argon2Hasher, err := NewArgon2id(cfg.Argon2)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		driver: cfg.Driver,
+		hashers: map[string]Hasher{
+			DriverBcrypt:   bcryptHasher,
+			DriverArgon2id: argon2Hasher,
+		},
+	}
+	if _, ok := m.hashers[cfg.Driver]; !ok {
+		return nil, fmt.Errorf("hash: unsupported driver %q (want bcrypt or argon2id)", cfg.Driver)
+	}
+	return m, nil
+}
+
+func (m *Manager) Hash(password string) (string, error) {
+	return m.hashers[m.driver].Hash(password)
+}
+
+func (m *Manager) Check(password, hashed string) (bool, error) {
+	h, ok := m.hashers[Identify(hashed)]
+	if !ok {
+		return false, ErrUnknownHash
+	}
+	return h.Check(password, hashed)
+}
+
+func (m *Manager) NeedsRehash(hashed string) bool {
+	algo := Identify(hashed)
+	if algo != m.driver {
+		return true
+	}
+	return m.hashers[algo].NeedsRehash(hashed)
+}
+
+// Identify 根据 PHC / Modular Crypt 前缀识别算法，无法识别时返回空串
+func Identify(hashed string) string {
+	switch {
+	case strings.HasPrefix(hashed, "$argon2id$"):
+		return DriverArgon2id
+	case strings.HasPrefix(hashed, "$2a$"), strings.HasPrefix(hashed, "$2b$"), strings.HasPrefix(hashed, "$2y$"):
+		return DriverBcrypt
+	default:
+		return ""
+	}
+}
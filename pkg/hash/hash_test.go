@@ -0,0 +1,85 @@
+package hash_test
+
+import (
+	"strings"
+	"testing"
+
+	"go-artisan/pkg/hash"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 测试里用最小参数，避免拖慢测试
+var fastArgon2 = hash.Argon2Params{Memory: 64, Time: 1, Threads: 1}
+
+func TestManager_HashAndCheck(t *testing.T) {
+	for _, driver := range []string{hash.DriverBcrypt, hash.DriverArgon2id} {
+		t.Run(driver, func(t *testing.T) {
+			m, err := hash.New(hash.Config{Driver: driver, BcryptCost: bcrypt.MinCost, Argon2: fastArgon2})
+			require.NoError(t, err)
+
+			hashed, err := m.Hash("secret123")
+			require.NoError(t, err)
+			assert.Equal(t, driver, hash.Identify(hashed))
+
+			ok, err := m.Check("secret123", hashed)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = m.Check("wrong", hashed)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			assert.False(t, m.NeedsRehash(hashed))
+		})
+	}
+}
+
+func TestManager_NeedsRehash(t *testing.T) {
+	oldBcrypt, err := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	argon, err := hash.New(hash.Config{Driver: hash.DriverArgon2id, BcryptCost: bcrypt.MinCost, Argon2: fastArgon2})
+	require.NoError(t, err)
+
+	// 换了算法：旧 bcrypt 哈希依然能校验，但需要重新哈希
+	ok, err := argon.Check("secret123", string(oldBcrypt))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, argon.NeedsRehash(string(oldBcrypt)))
+
+	// 调整了参数：同算法也需要重新哈希
+	hashed, err := argon.Hash("secret123")
+	require.NoError(t, err)
+	stronger, err := hash.New(hash.Config{Driver: hash.DriverArgon2id, Argon2: hash.Argon2Params{Memory: 128, Time: 1, Threads: 1}})
+	require.NoError(t, err)
+	assert.True(t, stronger.NeedsRehash(hashed))
+
+	bcryptCost, err := hash.New(hash.Config{Driver: hash.DriverBcrypt, BcryptCost: bcrypt.MinCost + 1})
+	require.NoError(t, err)
+	assert.True(t, bcryptCost.NeedsRehash(string(oldBcrypt)))
+
+	_, err = argon.Check("secret123", "plaintext")
+	assert.ErrorIs(t, err, hash.ErrUnknownHash)
+}
+
+func TestArgon2id_Check_RejectsZeroParams(t *testing.T) {
+	argon, err := hash.New(hash.Config{Driver: hash.DriverArgon2id, Argon2: fastArgon2})
+	require.NoError(t, err)
+	hashed, err := argon.Hash("secret123")
+	require.NoError(t, err)
+
+	for _, params := range []string{"m=0,t=1,p=1", "m=64,t=0,p=1", "m=64,t=1,p=0"} {
+		t.Run(params, func(t *testing.T) {
+			parts := strings.Split(hashed, "$")
+			parts[3] = params
+			assert.NotPanics(t, func() {
+				ok, err := argon.Check("secret123", strings.Join(parts, "$"))
+				assert.Error(t, err)
+				assert.False(t, ok)
+			})
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// SMTPMailer 通过 SMTP 发送，生产环境使用
+type SMTPMailer struct {
+	from     string // 邮件头中的发件人，可以带显示名，如 "GoArtisan <no-reply@example.com>"
+	envelope string // SMTP MAIL FROM 只能是纯地址
+	addr     string
+	host     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(from, host string, port int, username, password string) (*SMTPMailer, error) {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("mail: invalid from address %q: %w", from, err)
+	}
+	return &SMTPMailer{
+		from:     from,
+		envelope: sender.Address,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	// 防止邮件头注入
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("mail: invalid header value")
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	// net/smtp 不支持 context，这里用 goroutine 包一层以便请求取消时尽快返回
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(m.addr, auth, m.envelope, []string{msg.To}, render(m.from, msg))
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("mail: smtp send: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
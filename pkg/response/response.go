@@ -1,7 +1,13 @@
 package response
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"go-artisan/pkg/errs"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,6 +37,35 @@ func Error(c *gin.Context, httpStatus int, msg string) {
 	})
 }
 
+// Fail 按错误类型渲染响应：*errs.Error 使用其状态码和业务码，其他错误一律 500 且不暴露细节
+func Fail(c *gin.Context, err error) {
+	var e *errs.Error
+	if !errors.As(err, &e) {
+		c.JSON(http.StatusInternalServerError, Response{
+			Code:    errs.CodeInternal,
+			Message: "internal server error",
+			Data:    nil,
+		})
+		return
+	}
+
+	var data interface{}
+	if e.RetryAfter > 0 {
+		seconds := int(math.Ceil(e.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		data = gin.H{"retry_after": seconds}
+	}
+	if e.Field != "" {
+		data = map[string]string{e.Field: e.Message}
+	}
+
+	c.JSON(e.Kind.HTTPStatus(), Response{
+		Code:    e.BusinessCode(),
+		Message: e.Message,
+		Data:    data,
+	})
+}
+
 // ValidationError 表单验证失败响应
 // 模仿 Laravel: 422 Unprocessable Entity
 func ValidationError(c *gin.Context, errors interface{}) {
@@ -40,3 +75,13 @@ func ValidationError(c *gin.Context, errors interface{}) {
 		Data:    errors,
 	})
 }
+
+// InsufficientScope Token 权限范围不足 (403)，按 RFC 6750 返回 WWW-Authenticate 并列出缺少的 scope
+func InsufficientScope(c *gin.Context, missing []string) {
+	c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(missing, " ")+`"`)
+	c.JSON(http.StatusForbidden, Response{
+		Code:    http.StatusForbidden,
+		Message: "Insufficient scope",
+		Data:    gin.H{"missing_scopes": missing},
+	})
+}
@@ -0,0 +1,59 @@
+package urlsign_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"go-artisan/pkg/urlsign"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigner_Verify(t *testing.T) {
+	signer := urlsign.New("app-key")
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	signed := signer.Sign(url.Values{"id": {"42"}, "hash": {"abc"}}, now.Add(time.Hour))
+
+	tamper := func(key, value string) url.Values {
+		params := url.Values{}
+		for k, v := range signed {
+			params[k] = append([]string(nil), v...)
+		}
+		params.Set(key, value)
+		return params
+	}
+
+	tests := []struct {
+		name   string
+		signer *urlsign.Signer
+		params url.Values
+		now    time.Time
+		want   error
+	}{
+		{"有效", signer, signed, now, nil},
+		{"篡改参数", signer, tamper("id", "43"), now, urlsign.ErrInvalidSignature},
+		{"追加参数", signer, tamper("admin", "1"), now, urlsign.ErrInvalidSignature},
+		{"延长有效期", signer, tamper(urlsign.ParamExpires, "9999999999"), now, urlsign.ErrInvalidSignature},
+		{"签名不是十六进制", signer, tamper(urlsign.ParamSignature, "zz"), now, urlsign.ErrInvalidSignature},
+		{"缺少签名", signer, url.Values{"id": {"42"}}, now, urlsign.ErrInvalidSignature},
+		{"已过期", signer, signed, now.Add(time.Hour + time.Second), urlsign.ErrExpired},
+		{"不同密钥", urlsign.New("other-key"), signed, now, urlsign.ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.signer.Verify(tt.params, tt.now)
+			if tt.want == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSigner_Sign_DoesNotMutateInput(t *testing.T) {
+	params := url.Values{"id": {"42"}}
+	urlsign.New("app-key").Sign(params, time.Now())
+	assert.Equal(t, url.Values{"id": {"42"}}, params)
+}
@@ -0,0 +1,189 @@
+package validator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// 密码规则，同时也是注册到 validator 的子 tag 名
+const (
+	RulePasswordLength   = "password_length"
+	RulePasswordClasses  = "password_classes"
+	RulePasswordUserInfo = "password_userinfo"
+	RulePasswordBreached = "password_breached"
+)
+
+// bcryptMaxBytes bcrypt 只使用前 72 字节，超出部分会被静默忽略
+const bcryptMaxBytes = 72
+
+// PasswordPolicyConfig 密码策略参数
+type PasswordPolicyConfig struct {
+	MinLength        int
+	MaxLength        int // 按字节计算，默认 72
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	DisallowUserInfo bool   // 密码中不能包含邮箱或姓名
+	BreachedListFile string // 已泄露密码列表，每行一个，为空则不检查
+}
+
+// PasswordPolicy 密码策略，启动时加载一次泄露密码列表
+type PasswordPolicy struct {
+	cfg      PasswordPolicyConfig
+	breached map[string]struct{}
+}
+
+// PasswordPolicyError 违反的规则与说明
+type PasswordPolicyError struct {
+	Rule    string
+	Message string
+}
+
+func (e *PasswordPolicyError) Error() string { return e.Message }
+
+func NewPasswordPolicy(cfg PasswordPolicyConfig) (*PasswordPolicy, error) {
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = bcryptMaxBytes
+	}
+	if cfg.MinLength > cfg.MaxLength {
+		return nil, fmt.Errorf("password policy: min length %d exceeds max length %d", cfg.MinLength, cfg.MaxLength)
+	}
+
+	p := &PasswordPolicy{cfg: cfg, breached: map[string]struct{}{}}
+	if cfg.BreachedListFile != "" {
+		if err := p.loadBreached(cfg.BreachedListFile); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Check 校验密码，userInputs 为需要排除的用户信息 (邮箱、姓名)
+func (p *PasswordPolicy) Check(password string, userInputs ...string) error {
+	if !p.validLength(password) {
+		return p.violation(RulePasswordLength)
+	}
+	if !p.validClasses(password) {
+		return p.violation(RulePasswordClasses)
+	}
+	if !p.validUserInfo(password, userInputs...) {
+		return p.violation(RulePasswordUserInfo)
+	}
+	if !p.validBreached(password) {
+		return p.violation(RulePasswordBreached)
+	}
+	return nil
+}
+
+// Message 规则对应的提示 (不含字段名)
+func (p *PasswordPolicy) Message(rule string) string {
+	switch rule {
+	case RulePasswordLength:
+		return fmt.Sprintf("长度必须在%d到%d个字符之间", p.cfg.MinLength, p.cfg.MaxLength)
+	case RulePasswordClasses:
+		return "必须包含" + strings.Join(p.requiredClasses(), "、")
+	case RulePasswordUserInfo:
+		return "不能包含邮箱或姓名"
+	case RulePasswordBreached:
+		return "过于常见或已出现在泄露数据中，请更换"
+	default:
+		return "不符合密码策略"
+	}
+}
+
+func (p *PasswordPolicy) violation(rule string) error {
+	return &PasswordPolicyError{Rule: rule, Message: "密码" + p.Message(rule)}
+}
+
+// validLength 上限按字节计算 (bcrypt 72 字节限制)，下限按字符计算
+func (p *PasswordPolicy) validLength(password string) bool {
+	return len([]rune(password)) >= p.cfg.MinLength && len(password) <= p.cfg.MaxLength
+}
+
+func (p *PasswordPolicy) validClasses(password string) bool {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	return (!p.cfg.RequireUpper || upper) &&
+		(!p.cfg.RequireLower || lower) &&
+		(!p.cfg.RequireDigit || digit) &&
+		(!p.cfg.RequireSymbol || symbol)
+}
+
+// validUserInfo 邮箱同时检查完整地址和 @ 前的部分，过短 (<3) 的片段忽略以免误伤
+func (p *PasswordPolicy) validUserInfo(password string, userInputs ...string) bool {
+	if !p.cfg.DisallowUserInfo {
+		return true
+	}
+	lower := strings.ToLower(password)
+	for _, input := range userInputs {
+		input = strings.ToLower(strings.TrimSpace(input))
+		candidates := []string{input}
+		if local, _, ok := strings.Cut(input, "@"); ok {
+			candidates = append(candidates, local)
+		}
+		for _, c := range candidates {
+			if len([]rune(c)) >= 3 && strings.Contains(lower, c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (p *PasswordPolicy) validBreached(password string) bool {
+	_, found := p.breached[strings.ToLower(password)]
+	return !found
+}
+
+func (p *PasswordPolicy) requiredClasses() []string {
+	var classes []string
+	if p.cfg.RequireUpper {
+		classes = append(classes, "大写字母")
+	}
+	if p.cfg.RequireLower {
+		classes = append(classes, "小写字母")
+	}
+	if p.cfg.RequireDigit {
+		classes = append(classes, "数字")
+	}
+	if p.cfg.RequireSymbol {
+		classes = append(classes, "特殊字符")
+	}
+	return classes
+}
+
+func (p *PasswordPolicy) loadBreached(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("password policy: open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("password policy: read %s: %w", path, err)
+	}
+	return nil
+}
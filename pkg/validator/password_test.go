@@ -0,0 +1,79 @@
+package validator_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-artisan/pkg/validator"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicy(t *testing.T) *validator.PasswordPolicy {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(list, []byte("# comment\nPassword123\n"), 0o600))
+
+	policy, err := validator.NewPasswordPolicy(validator.PasswordPolicyConfig{
+		MinLength:        8,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		DisallowUserInfo: true,
+		BreachedListFile: list,
+	})
+	require.NoError(t, err)
+	return policy
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := newPolicy(t)
+
+	tests := []struct {
+		name     string
+		password string
+		rule     string
+	}{
+		{"合格", "Tr0ub4dor&3", ""},
+		{"太短", "Ab1", validator.RulePasswordLength},
+		{"超过 bcrypt 72 字节", "Aa1" + string(make([]byte, 70)), validator.RulePasswordLength},
+		{"缺少数字", "NoDigitsHere", validator.RulePasswordClasses},
+		{"包含邮箱前缀", "Xdaniel2024", validator.RulePasswordUserInfo},
+		{"泄露密码 (不区分大小写)", "pASSWORD123", validator.RulePasswordBreached},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Check(tt.password, "daniel@example.com", "Dan")
+			if tt.rule == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var perr *validator.PasswordPolicyError
+			require.ErrorAs(t, err, &perr)
+			assert.Equal(t, tt.rule, perr.Rule)
+		})
+	}
+}
+
+func TestPasswordTag(t *testing.T) {
+	validator.Init(newPolicy(t))
+
+	type registerRequest struct {
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password" binding:"required,password"`
+	}
+
+	// 同一结构体里的 Email / Name 参与校验，提示根据实际失败的子规则翻译
+	err := binding.Validator.ValidateStruct(&registerRequest{Name: "Dan", Email: "daniel@example.com", Password: "Daniel2024x"})
+	require.Error(t, err)
+	assert.Equal(t, "password不能包含邮箱或姓名", validator.Translate(err)["password"])
+
+	err = binding.Validator.ValidateStruct(&registerRequest{Password: "short"})
+	require.Error(t, err)
+	assert.Equal(t, "password长度必须在8到72个字符之间", validator.Translate(err)["password"])
+
+	assert.NoError(t, binding.Validator.ValidateStruct(&registerRequest{Name: "Dan", Email: "daniel@example.com", Password: "Tr0ub4dor&3"}))
+}
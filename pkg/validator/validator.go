@@ -14,8 +14,9 @@ import (
 // 全局变量存放翻译器
 var trans ut.Translator
 
-// Init 初始化验证器翻译 (在 main 或 bootstrap 中调用)
-func Init() {
+// Init 初始化验证器翻译与自定义规则 (在 main 或 bootstrap 中调用)
+// policy 为 nil 时不注册 password 规则
+func Init(policy *PasswordPolicy) {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		// 1. 注册 Tag Name 函数
 		v.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -33,7 +34,67 @@ func Init() {
 
 		// 3. 注册中文翻译
 		_ = zh_translations.RegisterDefaultTranslations(v, trans)
+
+		// 4. 注册自定义规则
+		if policy != nil {
+			registerPassword(v, policy)
+		}
+	}
+}
+
+// registerPassword 注册 password 规则，用法: binding:"required,password"
+// password 是多个子规则的别名，翻译时根据实际失败的子规则给出具体提示；
+// 同一结构体中的 Email / Name 字段会作为用户信息参与校验
+func registerPassword(v *validator.Validate, policy *PasswordPolicy) {
+	rules := map[string]validator.Func{
+		RulePasswordLength: func(fl validator.FieldLevel) bool {
+			return policy.validLength(fl.Field().String())
+		},
+		RulePasswordClasses: func(fl validator.FieldLevel) bool {
+			return policy.validClasses(fl.Field().String())
+		},
+		RulePasswordUserInfo: func(fl validator.FieldLevel) bool {
+			return policy.validUserInfo(fl.Field().String(), siblingStrings(fl, "Email", "Name")...)
+		},
+		RulePasswordBreached: func(fl validator.FieldLevel) bool {
+			return policy.validBreached(fl.Field().String())
+		},
+	}
+	for tag, fn := range rules {
+		_ = v.RegisterValidation(tag, fn)
+	}
+	v.RegisterAlias("password", strings.Join([]string{
+		RulePasswordLength, RulePasswordClasses, RulePasswordUserInfo, RulePasswordBreached,
+	}, ","))
+
+	_ = v.RegisterTranslation("password", trans,
+		func(ut ut.Translator) error {
+			return ut.Add("password", "{0}{1}", true)
+		},
+		func(ut ut.Translator, fe validator.FieldError) string {
+			msg, _ := ut.T("password", fe.Field(), policy.Message(fe.ActualTag()))
+			return msg
+		},
+	)
+}
+
+// siblingStrings 读取同一结构体中指定字段的字符串值
+func siblingStrings(fl validator.FieldLevel, names ...string) []string {
+	parent := fl.Parent()
+	for parent.Kind() == reflect.Ptr {
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var values []string
+	for _, name := range names {
+		if f := parent.FieldByName(name); f.IsValid() && f.Kind() == reflect.String {
+			values = append(values, f.String())
+		}
 	}
+	return values
 }
 
 // TranslateError 将校验错误转换为 Map